@@ -137,8 +137,13 @@ metadata:
 package main
 
 import (
+	"fmt"
 	"time"
+	"sort"
 	"strings"
+	"sync"
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/client-go/rest"
@@ -154,6 +159,21 @@ import (
 )
 
 var (
+	/* set via --acrq-poll-interval, 0 reads the quotas on demand during the scrape */
+	appliedClusterResourceQuotaPollInterval time.Duration
+	/* set via --acrq-parallelism, --acrq-request-timeout and --acrq-timeout for reading all namespaces */
+	appliedClusterResourceQuotaParallelism = 1
+	appliedClusterResourceQuotaRequestTimeout time.Duration
+	appliedClusterResourceQuotaTimeout time.Duration
+
+	AppliedClusterResourceQuotaRequestDurationHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "oapi_appliedclusterresourcequota_request_duration_seconds",
+			Help:    "Duration of reading the appliedclusterresourcequotas of a single namespace.",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+	)
+
 	descAppliedClusterResourceQuotaCreated = prometheus.NewDesc(
 		"oapi_appliedclusterresourcequota_created",
 		"Unix creation timestamp of clusterresourcequota",
@@ -202,36 +222,72 @@ func RegisterAppliedClusterResourceQuotaCollector(registry prometheus.Registerer
 	registry.MustRegister(&resourceQuotaCollector{store: resourceQuotaLister})
 }
 */
-func RegisterAppliedClusterResourceQuotaCollectorOApi(registry prometheus.Registerer, kubeConfig *rest.Config, namespace string) {
+func RegisterAppliedClusterResourceQuotaCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
 	 /* NOTE: appliedclusterresourcequata does not support watch and select by all namespaces*/
 
   /* for retrieving the current namespace list */
 	kubeClient, err := kubeclientset.NewForConfig(kubeConfig)
 	if err != nil {
-		glog.Fatalf("Failed to access kube api: %v", err)
+		return fmt.Errorf("failed to access kube api: %v", err)
 	}
   /* Note: OAPI only provides very specifiy clientsets */
 	quotaClient, err := quotav1clientset.NewForConfig(kubeConfig)
 	if err != nil {
-		glog.Fatalf("Failed to access quotas oapi: %v", err)
+		return fmt.Errorf("failed to access quotas api: %v", err)
 	}
 
-	glog.Infof("collect appliedclusterresourcequotas on demand")
-	
-	if (namespace == v1meta.NamespaceAll) {
-		glog.Infof("using appliedclusterresourcequotas for all namespace may be an performance issue. It is recommended to use clusterresourcequotas instead.")
+	if (appliedClusterResourceQuotaPollInterval == 0) {
+		glog.Infof("collect appliedclusterresourcequotas on demand")
+		if (gen.namespaces.informerNamespaces()[0] == v1meta.NamespaceAll && !gen.namespaces.bySelector()) {
+			glog.Infof("using appliedclusterresourcequotas for all namespace may be an performance issue. It is recommended to use clusterresourcequotas instead.")
+		}
+	} else {
+		glog.Infof("collect appliedclusterresourcequotas every %s", appliedClusterResourceQuotaPollInterval)
 	}
 	
   m := make(map[string]int)
-	registry.MustRegister(&resourceQuotaCollector{quotaclientset: quotaClient, kubeclientset: kubeClient, namespace: namespace, m: m})
+	rqc := &resourceQuotaCollector{quotaclientset: quotaClient, kubeclientset: kubeClient, namespaces: gen.namespaces, health: gen.health, m: m,
+		pollInterval: appliedClusterResourceQuotaPollInterval,
+		parallelism: appliedClusterResourceQuotaParallelism,
+		requestTimeout: appliedClusterResourceQuotaRequestTimeout,
+		timeout: appliedClusterResourceQuotaTimeout,
+	}
+	if (rqc.parallelism < 1) {
+		rqc.parallelism = 1
+	}
+	if err := registry.Register(rqc); err != nil {
+		return err
+	}
+	gen.health.addInformer("appliedclusterresourcequotas", rqc.hasSynced)
+
+	if (rqc.pollInterval > 0) {
+		go wait.Until(rqc.refresh, rqc.pollInterval, ctx.Done())
+	}
+	return nil
 }
 
 
 type resourceQuotaCollector struct {
-	namespace string
+	namespaces *namespaceSelection
+	health *collectorHealth
 	quotaclientset quotav1clientset.Interface
 	kubeclientset kubeclientset.Interface
 	m map[string]int
+
+	/* 0: read on demand in Collect, otherwise refreshed in the background */
+	pollInterval time.Duration
+	/* number of namespaces read concurrently, timeout per namespace and deadline for reading all namespaces (0: none) */
+	parallelism int
+	requestTimeout time.Duration
+	timeout time.Duration
+
+	/* snapshot per namespace, failed namespaces keep their last good quotas.
+	  lastRefresh only moves forward on a successful refresh, so the staleness shows a failing poller */
+	mu sync.Mutex
+	snapshot map[string][]quotav1meta.AppliedClusterResourceQuota
+	success bool
+	refreshed bool
+	lastRefresh time.Time
 }
 
 
@@ -240,73 +296,211 @@ func (rqc *resourceQuotaCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- descAppliedClusterResourceQuotaCreated
 	ch <- descAppliedClusterResourceQuotaSelector
 	ch <- descAppliedClusterResourceQuota
+	ch <- descCollectorSuccess
+	ch <- descCollectorLastRefresh
+	ch <- descCollectorStaleness
 }
 
 // Collect implements the prometheus.Collector interface.
 func (rqc *resourceQuotaCollector) Collect(ch chan<- prometheus.Metric) {
 	 /* NOTE: appliedclusterresourcequata does not support watch! */
-	quotaClient := rqc.quotaclientset
-	kubeClient := rqc.kubeclientset
-	
-  //m := make(map[string]int)
+	if rqc.pollInterval == 0 {
+		rqc.refresh()
+	}
+
+	rqc.mu.Lock()
+	defer rqc.mu.Unlock()
+
 	rqc.m = make(map[string]int)
 
+	names := make([]string, 0, len(rqc.snapshot))
+	for ns := range rqc.snapshot {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	for _, ns := range names {
+		for _, rq := range rqc.snapshot[ns] {
+			rqc.collectAppliedClusterResourceQuota(ch, rq)
+		}
+	}
+
+	/* API errors only skip the failed namespace, they are reported via oapi_collector_success */
+	ch <- prometheus.MustNewConstMetric(descCollectorSuccess, prometheus.GaugeValue, boolFloat64(rqc.success), "appliedclusterresourcequotas")
+	if !rqc.lastRefresh.IsZero() {
+		ch <- prometheus.MustNewConstMetric(descCollectorLastRefresh, prometheus.GaugeValue, float64(rqc.lastRefresh.Unix()), "appliedclusterresourcequotas")
+		ch <- prometheus.MustNewConstMetric(descCollectorStaleness, prometheus.GaugeValue, time.Since(rqc.lastRefresh).Seconds(), "appliedclusterresourcequotas")
+	}
+}
+
+/* hasSynced: in the background mode after the first refresh, also a failed one, on demand always */
+func (rqc *resourceQuotaCollector) hasSynced() bool {
+	if rqc.pollInterval == 0 {
+		return true
+	}
+	rqc.mu.Lock()
+	defer rqc.mu.Unlock()
+	return rqc.refreshed
+}
+
+// refresh reads the appliedclusterresourcequotas of the selected namespaces and replaces the snapshot served by Collect.
+func (rqc *resourceQuotaCollector) refresh() {
+	quotaClient := rqc.quotaclientset
+	kubeClient := rqc.kubeclientset
 
-	if rqc.namespace == v1meta.NamespaceAll {
+	rqc.mu.Lock()
+	previous := rqc.snapshot
+	rqc.mu.Unlock()
+
+	snapshot := make(map[string][]quotav1meta.AppliedClusterResourceQuota)
+	success := true
+	defer func() {
+		rqc.mu.Lock()
+		/* on a failed namespace list the previous snapshot is kept */
+		if snapshot != nil {
+			rqc.snapshot = snapshot
+		}
+		rqc.success = success
+		rqc.refreshed = true
+		if success {
+			rqc.lastRefresh = time.Now()
+		}
+		rqc.mu.Unlock()
+		/* not under rqc.mu, the health takes its lock before hasSynced */
+		if success {
+			rqc.health.setSourceError("appliedclusterresourcequotas", "", nil)
+		}
+	}()
 
-	 	/* collect metrics for execution times */
-	 	start := time.Now()
+	/* collect metrics for execution times */
+	start := time.Now()
 
+	names := rqc.namespaces.informerNamespaces()
+	if rqc.namespaces.bySelector() {
+		/* the namespaces of the selected projects, no cluster wide namespace list needed */
+		names = rqc.namespaces.selectedNamespaces()
+	} else if names[0] == v1meta.NamespaceAll {
 	 	namespaceList, err := kubeClient.CoreV1().Namespaces().List(v1meta.ListOptions{})
 	 	if err != nil {
-			glog.Fatalf("Failed to list namespaces: %v", err)
+			glog.Errorf("Failed to list namespaces: %v", err)
+			rqc.health.setError("appliedclusterresourcequotas", err)
+			ScrapeErrorTotalMetric.WithLabelValues("appliedclusterresourcequotas ns list").Inc()
+			success = false
+			snapshot = nil
+			return
 	 	}
+		names = nil
+		for _, ns := range namespaceList.Items {
+			names = append(names, ns.Name)
+		}
 
 	 	duration := time.Since(start)
 	 	ScrapeDurationHistogram.WithLabelValues("appliedclusterresourcequotas ns list").Observe(duration.Seconds())
 	 	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "appliedclusterresourcequotas ns list"}).Observe(float64(len(namespaceList.Items)))
 
 	 	start = time.Now()
-	 	var ns_count int 
-	 	ns_count = 0
-	 	for _, ns := range namespaceList.Items {
-			resourceQuota, err := quotaClient.QuotaV1().AppliedClusterResourceQuotas(ns.Name).List(v1meta.ListOptions{})
-			if err != nil {
-				glog.Fatalf("Failed to read quotas: %v", err)
-			}
-		
-			for _, rq := range resourceQuota.Items {
-				rqc.collectAppliedClusterResourceQuota(ch, rq)
-			}
-			ns_count = ns_count + len(resourceQuota.Items)
-	 	}
-
-	 	duration = time.Since(start)
-	 	ScrapeDurationHistogram.WithLabelValues("appliedclusterresourcequotas").Observe(duration.Seconds())
-	 	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "appliedclusterresourcequotas"}).Observe(float64(ns_count))
-	 
-	 
-	 	glog.Infof("collected %d appliedclusterresourcequotas for %s", ns_count , "all namespaces")
-	} else {
-
-	  /* collect metrics for execution times */
-	  start := time.Now()
+	}
 
-	  resourceQuota, err := quotaClient.QuotaV1().AppliedClusterResourceQuotas(rqc.namespace).List(v1meta.ListOptions{})
-		if err != nil {
-			glog.Fatalf("Failed to read quotas: %v", err)
+	/* only read the namespaces passing the selector and exclude regex */
+	selected := []string{}
+	for _, ns := range names {
+		if rqc.namespaces.matches(ns) {
+			selected = append(selected, ns)
 		}
+	}
 
-		for _, rq := range resourceQuota.Items {
-			rqc.collectAppliedClusterResourceQuota(ch, rq)
+	/* bounded fan-out over the namespaces, results and errors are merged under resultMu */
+	var deadline <-chan time.Time
+	var deadlineAt time.Time
+	if rqc.timeout > 0 {
+		deadline = time.After(rqc.timeout)
+		deadlineAt = start.Add(rqc.timeout)
+	}
+	var wg sync.WaitGroup
+	var resultMu sync.Mutex
+	namespaces := make(chan string)
+	for i := 0; i < rqc.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ns := range namespaces {
+				nsStart := time.Now()
+				/* in-flight requests are cancelled at the deadline of all namespaces */
+				timeout := rqc.requestTimeout
+				if !deadlineAt.IsZero() {
+					left := deadlineAt.Sub(nsStart)
+					if left <= 0 {
+						left = -1
+					}
+					if timeout == 0 || left < timeout {
+						timeout = left
+					}
+				}
+				resourceQuota, err := listAppliedClusterResourceQuotas(quotaClient, ns, timeout)
+				AppliedClusterResourceQuotaRequestDurationHistogram.Observe(time.Since(nsStart).Seconds())
+
+				resultMu.Lock()
+				if err != nil {
+					glog.Errorf("Failed to read quotas of namespace %s: %v", ns, err)
+					rqc.health.setError("appliedclusterresourcequotas", fmt.Errorf("namespace %s: %v", ns, err))
+					ScrapeErrorTotalMetric.WithLabelValues("appliedclusterresourcequotas").Inc()
+					success = false
+					if quotas, ok := previous[ns]; ok {
+						snapshot[ns] = quotas
+					}
+				} else {
+					snapshot[ns] = resourceQuota.Items
+				}
+				resultMu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i, ns := range selected {
+		select {
+		case namespaces <- ns:
+		case <-deadline:
+			glog.Errorf("Reading appliedclusterresourcequotas exceeded %s, skipped %d namespaces", rqc.timeout, len(selected)-i)
+			rqc.health.setError("appliedclusterresourcequotas", fmt.Errorf("exceeded %s, skipped %d namespaces", rqc.timeout, len(selected)-i))
+			ScrapeErrorTotalMetric.WithLabelValues("appliedclusterresourcequotas").Inc()
+			resultMu.Lock()
+			success = false
+			/* the skipped namespaces keep their last good quotas */
+			for _, skipped := range selected[i:] {
+				if quotas, ok := previous[skipped]; ok {
+					snapshot[skipped] = quotas
+				}
+			}
+			resultMu.Unlock()
+			break dispatch
 		}
+	}
+	close(namespaces)
+	wg.Wait()
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("appliedclusterresourcequotas").Observe(duration.Seconds())
+	count := 0
+	for _, quotas := range snapshot {
+		count += len(quotas)
+	}
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "appliedclusterresourcequotas"}).Observe(float64(count))
 
-		duration := time.Since(start)
-		ScrapeDurationHistogram.WithLabelValues("appliedclusterresourcequotas").Observe(duration.Seconds())
-		ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "appliedclusterresourcequotas"}).Observe(float64(len(resourceQuota.Items)))
-		glog.Infof("collected %d appliedclusterresourcequotas for %s", len(resourceQuota.Items), rqc.namespace)
+	glog.Infof("collected %d appliedclusterresourcequotas for %s", count, rqc.namespaces)
+}
 
-  	}
+/* listAppliedClusterResourceQuotas: the typed List has no per request timeout, so the request is built here */
+func listAppliedClusterResourceQuotas(quotaClient quotav1clientset.Interface, ns string, timeout time.Duration) (*quotav1meta.AppliedClusterResourceQuotaList, error) {
+	if timeout < 0 {
+		return nil, fmt.Errorf("deadline exceeded")
+	}
+	req := quotaClient.QuotaV1().RESTClient().Get().Namespace(ns).Resource("appliedclusterresourcequotas")
+	if timeout > 0 {
+		req = req.Timeout(timeout)
+	}
+	result := &quotav1meta.AppliedClusterResourceQuotaList{}
+	err := req.Do().Into(result)
+	return result, err
 }
 
 func (rqc *resourceQuotaCollector) collectAppliedClusterResourceQuota(ch chan<- prometheus.Metric, rql quotav1meta.AppliedClusterResourceQuota) {
@@ -315,7 +509,7 @@ func (rqc *resourceQuotaCollector) collectAppliedClusterResourceQuota(ch chan<-
 
 	for _, rq := range rql.Status.Namespaces { 
 
-	if (rqc.namespace == rq.Namespace || rqc.namespace == v1meta.NamespaceAll) {
+	if (rqc.namespaces.matches(rq.Namespace)) {
 	 // only include metrics from selected namespaces:
 
 
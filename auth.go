@@ -0,0 +1,184 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	authenticationclient "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+const (
+	/* reviews per second and burst, independent of --kube-api-qps, so unknown tokens cannot use up the API budget of the collectors */
+	metricsAuthReviewQPS   = 5
+	metricsAuthReviewBurst = 10
+	/* maximum number of cached decisions */
+	metricsAuthCacheSize = 1000
+)
+
+/* metricsAuthorizer: authenticates the bearer token of a request via TokenReview and authorizes
+  the user via SubjectAccessReview on a non-resource URL, like kube-rbac-proxy does */
+type metricsAuthorizer struct {
+	authnClient authenticationclient.TokenReviewsGetter
+	authzClient authorizationclient.SubjectAccessReviewsGetter
+	verb        string
+	path        string
+	limiter     flowcontrol.RateLimiter
+
+	/* decisions of authenticated tokens per token hash, so not every scrape causes two API calls */
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	cache    map[[sha256.Size]byte]authDecision
+}
+
+type authDecision struct {
+	status  int
+	expires time.Time
+}
+
+func newMetricsAuthorizer(kubeConfig *rest.Config, verb, path string, cacheTTL time.Duration) (*metricsAuthorizer, error) {
+	/* the reviews are limited by the authorizer itself, not by the limiter shared with the collectors */
+	reviewConfig := *kubeConfig
+	reviewConfig.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	authnClient, err := authenticationclient.NewForConfig(&reviewConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access authentication api: %v", err)
+	}
+	authzClient, err := authorizationclient.NewForConfig(&reviewConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access authorization api: %v", err)
+	}
+	return &metricsAuthorizer{
+		authnClient: authnClient,
+		authzClient: authzClient,
+		verb:        verb,
+		path:        path,
+		limiter:     flowcontrol.NewTokenBucketRateLimiter(metricsAuthReviewQPS, metricsAuthReviewBurst),
+		cacheTTL:    cacheTTL,
+		cache:       make(map[[sha256.Size]byte]authDecision),
+	}, nil
+}
+
+/* authorize returns the HTTP status for the token: 200, 401, 403 or 429 if too many reviews are requested */
+func (a *metricsAuthorizer) authorize(token string) int {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	a.mu.Lock()
+	d, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && now.Before(d.expires) {
+		return d.status
+	}
+
+	if !a.limiter.TryAccept() {
+		glog.V(2).Infof("Too many metrics token reviews, rejecting request")
+		return http.StatusTooManyRequests
+	}
+	status, err := a.review(token)
+	if err != nil {
+		/* API errors are not cached */
+		glog.Errorf("Failed to authorize metrics request: %v", err)
+		return http.StatusInternalServerError
+	}
+
+	/* failed authentications are not cached, random tokens would fill the cache */
+	if status == http.StatusUnauthorized {
+		return status
+	}
+	a.mu.Lock()
+	for k, d := range a.cache {
+		if now.After(d.expires) {
+			delete(a.cache, k)
+		}
+	}
+	if len(a.cache) >= metricsAuthCacheSize {
+		for k := range a.cache {
+			delete(a.cache, k)
+			break
+		}
+	}
+	a.cache[key] = authDecision{status: status, expires: now.Add(a.cacheTTL)}
+	a.mu.Unlock()
+	return status
+}
+
+func (a *metricsAuthorizer) review(token string) (int, error) {
+	tr, err := a.authnClient.TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !tr.Status.Authenticated {
+		return http.StatusUnauthorized, nil
+	}
+
+	user := tr.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := a.authzClient.SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: a.path,
+				Verb: a.verb,
+			},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !sar.Status.Allowed {
+		glog.V(2).Infof("Metrics access denied for %s: %s", user.Username, sar.Status.Reason)
+		return http.StatusForbidden, nil
+	}
+	return http.StatusOK, nil
+}
+
+/* handler wraps next, requests without an allowed bearer token are rejected */
+func (a *metricsAuthorizer) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || strings.TrimSpace(auth[len("Bearer "):]) == "" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if status := a.authorize(strings.TrimSpace(auth[len("Bearer "):])); status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/rest"
+
+	buildv1meta "github.com/openshift/api/build/v1"
+	buildv1clientset "github.com/openshift/client-go/build/clientset/versioned"
+)
+
+const (
+	/* set by the build controller on builds created from a BuildConfig */
+	buildConfigNameAnnotation = "openshift.io/build-config.name"
+)
+
+var (
+	descBuildLabelsName          = "oapi_build_labels"
+	descBuildLabelsHelp          = "Build labels converted to Prometheus labels."
+	descBuildLabelsDefaultLabels = []string{"namespace", "buildconfig", "build"}
+
+	buildPhases = []buildv1meta.BuildPhase{
+		buildv1meta.BuildPhaseNew,
+		buildv1meta.BuildPhasePending,
+		buildv1meta.BuildPhaseRunning,
+		buildv1meta.BuildPhaseComplete,
+		buildv1meta.BuildPhaseFailed,
+		buildv1meta.BuildPhaseError,
+		buildv1meta.BuildPhaseCancelled,
+	}
+
+	descBuildCreated = prometheus.NewDesc(
+		"oapi_build_created",
+		"Unix creation timestamp of Build",
+		[]string{"namespace", "buildconfig", "build"}, nil,
+	)
+
+	descBuildStatusPhase = prometheus.NewDesc(
+		"oapi_build_status_phase",
+		"The current phase of the Build.",
+		[]string{"namespace", "buildconfig", "build", "phase"}, nil,
+	)
+
+	descBuildStatusStartTimestamp = prometheus.NewDesc(
+		"oapi_build_status_start_timestamp",
+		"Unix timestamp when the Build started running.",
+		[]string{"namespace", "buildconfig", "build"}, nil,
+	)
+
+	descBuildStatusCompletionTimestamp = prometheus.NewDesc(
+		"oapi_build_status_completion_timestamp",
+		"Unix timestamp when the Build completed.",
+		[]string{"namespace", "buildconfig", "build"}, nil,
+	)
+
+	descBuildStatusDurationSeconds = prometheus.NewDesc(
+		"oapi_build_status_duration_seconds",
+		"Duration of the Build in seconds. For running builds the time since start.",
+		[]string{"namespace", "buildconfig", "build"}, nil,
+	)
+
+	descBuildStatusReason = prometheus.NewDesc(
+		"oapi_build_status_reason",
+		"Brief reason why the Build is in its current phase, e.g. the failure reason.",
+		[]string{"namespace", "buildconfig", "build", "reason"}, nil,
+	)
+
+	descBuildLabels = prometheus.NewDesc(
+		descBuildLabelsName,
+		descBuildLabelsHelp,
+		descBuildLabelsDefaultLabels, nil,
+	)
+)
+
+
+type BuildLister func() ([]buildv1meta.Build, error)
+
+func (l BuildLister) List() ([]buildv1meta.Build, error) {
+	return l()
+}
+
+func RegisterBuildCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
+
+	/* Note: OAPI only provides very specifiy clientsets */
+	buildClient, err := buildv1clientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access builds api: %v", err)
+	}
+
+	client := buildClient.BuildV1().RESTClient()
+
+	binf := newNamespacedInformer(client, "builds", &buildv1meta.Build{}, gen)
+
+	buildLister := BuildLister(func() (builds []buildv1meta.Build, err error) {
+		for _, b := range binf.List() {
+			builds = append(builds, *(b.(*buildv1meta.Build)))
+		}
+		return builds, nil
+	})
+
+	if err := registry.Register(&buildCollector{store: buildLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("builds", binf.HasSynced)
+	go binf.Run(ctx.Done())
+	return nil
+}
+
+type buildStore interface {
+	List() ([]buildv1meta.Build, error)
+}
+
+// buildCollector collects metrics about all builds in the cluster.
+type buildCollector struct {
+	store buildStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (bc *buildCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descBuildCreated
+	ch <- descBuildStatusPhase
+	ch <- descBuildStatusStartTimestamp
+	ch <- descBuildStatusCompletionTimestamp
+	ch <- descBuildStatusDurationSeconds
+	ch <- descBuildStatusReason
+	ch <- descBuildLabels
+}
+
+// Collect implements the prometheus.Collector interface.
+func (bc *buildCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	bs, err := bc.store.List()
+	if err != nil {
+		glog.Errorf("listing builds failed: %s", err)
+		return
+	}
+
+	for _, b := range bs {
+		bc.collectBuild(ch, b)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("build").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "build"}).Observe(float64(len(bs)))
+
+	glog.Infof("collected %d builds", len(bs))
+}
+
+func buildLabelsDesc(labelKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descBuildLabelsName,
+		descBuildLabelsHelp,
+		append(descBuildLabelsDefaultLabels, labelKeys...),
+		nil,
+	)
+}
+
+/* buildConfigName: name of the BuildConfig the build was started from, empty for standalone builds */
+func buildConfigName(b buildv1meta.Build) string {
+	if b.Status.Config != nil {
+		return b.Status.Config.Name
+	}
+	if name, ok := b.Annotations[buildConfigNameAnnotation]; ok {
+		return name
+	}
+	return b.Labels[buildConfigNameAnnotation]
+}
+
+
+func (bc *buildCollector) collectBuild(ch chan<- prometheus.Metric, b buildv1meta.Build) {
+	buildConfig := buildConfigName(b)
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{b.Namespace, buildConfig, b.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("builds", b.Labels)
+	addGauge(buildLabelsDesc(labelKeys), 1, labelValues...)
+	if !b.CreationTimestamp.IsZero() {
+		addGauge(descBuildCreated, float64(b.CreationTimestamp.Unix()))
+	}
+
+	for _, p := range buildPhases {
+		addGauge(descBuildStatusPhase, boolFloat64(b.Status.Phase == p), string(p))
+	}
+
+	if b.Status.StartTimestamp != nil {
+		addGauge(descBuildStatusStartTimestamp, float64(b.Status.StartTimestamp.Unix()))
+	}
+	if b.Status.CompletionTimestamp != nil {
+		addGauge(descBuildStatusCompletionTimestamp, float64(b.Status.CompletionTimestamp.Unix()))
+	}
+
+	if b.Status.Duration > 0 {
+		addGauge(descBuildStatusDurationSeconds, b.Status.Duration.Seconds())
+	} else if b.Status.StartTimestamp != nil && b.Status.CompletionTimestamp != nil {
+		addGauge(descBuildStatusDurationSeconds, b.Status.CompletionTimestamp.Sub(b.Status.StartTimestamp.Time).Seconds())
+	} else if b.Status.StartTimestamp != nil {
+		addGauge(descBuildStatusDurationSeconds, time.Since(b.Status.StartTimestamp.Time).Seconds())
+	}
+
+	if b.Status.Reason != "" {
+		addGauge(descBuildStatusReason, 1, string(b.Status.Reason))
+	}
+}
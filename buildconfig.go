@@ -0,0 +1,205 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/rest"
+
+	buildv1meta "github.com/openshift/api/build/v1"
+	buildv1clientset "github.com/openshift/client-go/build/clientset/versioned"
+)
+
+var (
+	descBuildConfigLabelsName          = "oapi_buildconfig_labels"
+	descBuildConfigLabelsHelp          = "BuildConfig labels converted to Prometheus labels."
+	descBuildConfigLabelsDefaultLabels = []string{"namespace", "buildconfig"}
+
+	descBuildConfigCreated = prometheus.NewDesc(
+		"oapi_buildconfig_created",
+		"Unix creation timestamp of BuildConfig",
+		[]string{"namespace", "buildconfig"}, nil,
+	)
+
+	descBuildConfigMetadataGeneration = prometheus.NewDesc(
+		"oapi_buildconfig_metadata_generation",
+		"Sequence number representing a specific generation of the desired state.",
+		[]string{"namespace", "buildconfig"}, nil,
+	)
+
+	descBuildConfigStatusLastVersion = prometheus.NewDesc(
+		"oapi_buildconfig_status_last_version",
+		"The number of the last build triggered from the BuildConfig.",
+		[]string{"namespace", "buildconfig"}, nil,
+	)
+
+	descBuildConfigSpecStrategyType = prometheus.NewDesc(
+		"oapi_buildconfig_spec_strategy_type",
+		"The build strategy type (Docker, Source, Custom, JenkinsPipeline) of the BuildConfig.",
+		[]string{"namespace", "buildconfig", "strategy"}, nil,
+	)
+
+	descBuildConfigSpecSourceType = prometheus.NewDesc(
+		"oapi_buildconfig_spec_source_type",
+		"The build source type (Git, Dockerfile, Binary, Image, None) of the BuildConfig.",
+		[]string{"namespace", "buildconfig", "source"}, nil,
+	)
+
+	descBuildConfigSpecTrigger = prometheus.NewDesc(
+		"oapi_buildconfig_spec_trigger",
+		"The triggers which launch new builds from the BuildConfig.",
+		[]string{"namespace", "buildconfig", "type"}, nil,
+	)
+
+	descBuildConfigSpecRunPolicy = prometheus.NewDesc(
+		"oapi_buildconfig_spec_run_policy",
+		"The policy (Serial, Parallel, SerialLatestOnly) used to schedule new builds of the BuildConfig.",
+		[]string{"namespace", "buildconfig", "run_policy"}, nil,
+	)
+
+	descBuildConfigLabels = prometheus.NewDesc(
+		descBuildConfigLabelsName,
+		descBuildConfigLabelsHelp,
+		descBuildConfigLabelsDefaultLabels, nil,
+	)
+)
+
+
+type BuildConfigLister func() ([]buildv1meta.BuildConfig, error)
+
+func (l BuildConfigLister) List() ([]buildv1meta.BuildConfig, error) {
+	return l()
+}
+
+func RegisterBuildConfigCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
+
+	/* Note: OAPI only provides very specifiy clientsets */
+	buildClient, err := buildv1clientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access buildconfigs api: %v", err)
+	}
+
+	client := buildClient.BuildV1().RESTClient()
+
+	bcinf := newNamespacedInformer(client, "buildconfigs", &buildv1meta.BuildConfig{}, gen)
+
+	buildConfigLister := BuildConfigLister(func() (buildconfigs []buildv1meta.BuildConfig, err error) {
+		for _, bc := range bcinf.List() {
+			buildconfigs = append(buildconfigs, *(bc.(*buildv1meta.BuildConfig)))
+		}
+		return buildconfigs, nil
+	})
+
+	if err := registry.Register(&buildConfigCollector{store: buildConfigLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("buildconfigs", bcinf.HasSynced)
+	go bcinf.Run(ctx.Done())
+	return nil
+}
+
+type buildConfigStore interface {
+	List() ([]buildv1meta.BuildConfig, error)
+}
+
+// buildConfigCollector collects metrics about all buildconfigs in the cluster.
+type buildConfigCollector struct {
+	store buildConfigStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (bcc *buildConfigCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descBuildConfigCreated
+	ch <- descBuildConfigMetadataGeneration
+	ch <- descBuildConfigStatusLastVersion
+	ch <- descBuildConfigSpecStrategyType
+	ch <- descBuildConfigSpecSourceType
+	ch <- descBuildConfigSpecTrigger
+	ch <- descBuildConfigSpecRunPolicy
+	ch <- descBuildConfigLabels
+}
+
+// Collect implements the prometheus.Collector interface.
+func (bcc *buildConfigCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	bcs, err := bcc.store.List()
+	if err != nil {
+		glog.Errorf("listing buildconfigs failed: %s", err)
+		return
+	}
+
+	for _, bc := range bcs {
+		bcc.collectBuildConfig(ch, bc)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("buildconfig").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "buildconfig"}).Observe(float64(len(bcs)))
+
+	glog.Infof("collected %d buildconfigs", len(bcs))
+}
+
+func buildConfigLabelsDesc(labelKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descBuildConfigLabelsName,
+		descBuildConfigLabelsHelp,
+		append(descBuildConfigLabelsDefaultLabels, labelKeys...),
+		nil,
+	)
+}
+
+
+func (bcc *buildConfigCollector) collectBuildConfig(ch chan<- prometheus.Metric, bc buildv1meta.BuildConfig) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{bc.Namespace, bc.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("buildconfigs", bc.Labels)
+	addGauge(buildConfigLabelsDesc(labelKeys), 1, labelValues...)
+	if !bc.CreationTimestamp.IsZero() {
+		addGauge(descBuildConfigCreated, float64(bc.CreationTimestamp.Unix()))
+	}
+	addGauge(descBuildConfigMetadataGeneration, float64(bc.ObjectMeta.Generation))
+	addGauge(descBuildConfigStatusLastVersion, float64(bc.Status.LastVersion))
+	addGauge(descBuildConfigSpecStrategyType, 1, string(bc.Spec.Strategy.Type))
+	addGauge(descBuildConfigSpecSourceType, 1, string(bc.Spec.Source.Type))
+
+	/* the same trigger type may be defined several times (e.g. multiple webhooks) */
+	triggers := make(map[string]struct{})
+	for _, t := range bc.Spec.Triggers {
+		triggers[string(t.Type)] = struct{}{}
+	}
+	for t := range triggers {
+		addGauge(descBuildConfigSpecTrigger, 1, t)
+	}
+
+	runPolicy := bc.Spec.RunPolicy
+	if runPolicy == "" {
+		runPolicy = buildv1meta.BuildRunPolicySerial
+	}
+	addGauge(descBuildConfigSpecRunPolicy, 1, string(runPolicy))
+}
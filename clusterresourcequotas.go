@@ -18,6 +18,7 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
 	"time"
 	"strings"
 
@@ -73,12 +74,12 @@ func (l ClusterResourceQuotaLister) List() ([]quotav1meta.ClusterResourceQuota,
 /*  RegisterClusterResourceQuotaCollectorOApi: register collector for ClusterResourceQuotas
   NOTE: clusterresourcequata does not support watch and select by all namespaces
 */
-func RegisterClusterResourceQuotaCollectorOApi(registry prometheus.Registerer, kubeConfig *rest.Config, namespace string) {
+func RegisterClusterResourceQuotaCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
 
 
 	authKubeClient, err := authorizationclient.NewForConfig(kubeConfig)
 	if err != nil {
-		glog.Fatalf("Failed to access quotas api: %v", err)
+		return fmt.Errorf("failed to access authorization api: %v", err)
 	}
 
    /* Check if current kubernetes user, can access clusterresource quotas
@@ -95,22 +96,22 @@ func RegisterClusterResourceQuotaCollectorOApi(registry prometheus.Registerer, k
    // AuthorizationV1().
    rssar, err := authKubeClient.SelfSubjectAccessReviews().Create(ssar)
    if err != nil {
-	   glog.Fatalf("Cannot access ssar for clusterresourcequotas: %v", err)
-	   return
+	   return fmt.Errorf("cannot access ssar for clusterresourcequotas: %v", err)
    }
 	
     if (rssar.Status.Allowed == false) {
 	  glog.Infof("%v", rssar.Status.Reason)
 	  glog.Infof("Needs cluster-reader ClusterRole or cluster RBAC get clusterresourcequota. Alternatively use applicedclusterresourcequotas instead")
+	  gen.health.setError("clusterresourcequotas", fmt.Errorf("not allowed to get clusterresourcequotas: %s", rssar.Status.Reason))
 
     } else {	
 	
 		/* Note: OAPI only provides very specifiy clientsets */
 		clusterresourcequotaClient, err := clusterresourcequotav1meta.NewForConfig(kubeConfig)
 		if err != nil {
-		    glog.Fatalf("Failed to access clusterresourcequotas api: %v", err)
+		    return fmt.Errorf("failed to access clusterresourcequotas api: %v", err)
 		}		
-		resyncPeriod, _ := time.ParseDuration("0h0m30s")		
+		resyncPeriod := gen.resyncPeriod("clusterresourcequotas")
 		client := clusterresourcequotaClient.QuotaV1().RESTClient()		
 		// note: namespace not supported here, filter at collection
 		rqlw := cache.NewListWatchFromClient(client, "clusterresourcequotas", "", fields.Everything())
@@ -122,9 +123,13 @@ func RegisterClusterResourceQuotaCollectorOApi(registry prometheus.Registerer, k
 			return clusterresourcequotas, nil
 		})		
 		m := make(map[string]int)
-		registry.MustRegister(&clusterResourceQuotaCollector{store: clusterResourceQuotaLister, m: m, namespace: namespace})
-		go rqinf.Run(context.Background().Done())
+		if err := registry.Register(&clusterResourceQuotaCollector{store: clusterResourceQuotaLister, m: m, namespaces: gen.namespaces}); err != nil {
+			return err
+		}
+		gen.health.addInformer("clusterresourcequotas", rqinf.HasSynced)
+		go rqinf.Run(ctx.Done())
     }
+    return nil
 }
 
 type clusterResourceQuotaStore interface {
@@ -134,7 +139,7 @@ type clusterResourceQuotaStore interface {
 // clusterResourceQuotaCollector collects metrics about all resource clusterresourcequotas in the cluster.
 type clusterResourceQuotaCollector struct {
 	store clusterResourceQuotaStore
-	namespace string
+	namespaces *namespaceSelection
 	m map[string]int
 }
 
@@ -175,7 +180,7 @@ func (rqc *clusterResourceQuotaCollector) Collect(ch chan<- prometheus.Metric) {
 func (rqc *clusterResourceQuotaCollector) collectClusterResourceQuota(ch chan<- prometheus.Metric, rql quotav1meta.ClusterResourceQuota) {
 
 		//glog.Infof("m before %s", rqc.m)
-		nsfound := (rqc.namespace == v1meta.NamespaceAll)
+		nsfound := rqc.namespaces.isAll()
 
 		sel := rql.Spec.Selector
 		for key, value := range sel.AnnotationSelector {
@@ -193,7 +198,7 @@ func (rqc *clusterResourceQuotaCollector) collectClusterResourceQuota(ch chan<-
 		}
 		
 		for _, rq := range rql.Status.Namespaces { 
-			if (rqc.namespace == rq.Namespace || rqc.namespace == v1meta.NamespaceAll)  {
+			if (rqc.namespaces.matches(rq.Namespace))  {
 			 	nsfound = true
 			 	_, ok := rqc.m[strings.Join([]string{rql.Name, rq.Namespace},"/")]
 			 	if !(ok)  {	
@@ -0,0 +1,398 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	ConfigLastReloadSuccessMetric = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oapi_config_last_reload_successful",
+			Help: "Whether the last reload of the --config file succeeded.",
+		},
+	)
+
+	ConfigLastReloadSuccessTimestampMetric = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oapi_config_last_reload_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful reload of the --config file.",
+		},
+	)
+)
+
+// resyncPeriodMap is the resync period per collector, set via a flag like 'deploymentconfigs=5m,routes=1h'.
+type resyncPeriodMap map[string]time.Duration
+
+func (m *resyncPeriodMap) String() string {
+	s := []string{}
+	for col, d := range *m {
+		s = append(s, col+"="+d.String())
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (m *resyncPeriodMap) Set(value string) error {
+	s := *m
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid resync period %q, expected <collector>=<duration>", entry)
+		}
+		col := strings.TrimSpace(parts[0])
+		if !collectorExists(col) {
+			return fmt.Errorf("collector %q does not exist", col)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid resync period of collector %s: %v", col, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("resync period of collector %s must not be negative", col)
+		}
+		s[col] = d
+	}
+	return nil
+}
+
+func (m *resyncPeriodMap) Type() string {
+	return "string"
+}
+
+/* fileConfig: the --config YAML file, set fields override the flags */
+type fileConfig struct {
+	Collectors                 []string                   `json:"collectors,omitempty"`
+	Namespaces                 []string                   `json:"namespaces,omitempty"`
+	NamespaceSelector          *string                    `json:"namespaceSelector,omitempty"`
+	NamespaceExcludeRegex      *string                    `json:"namespaceExcludeRegex,omitempty"`
+	MetricWhitelist            []string                   `json:"metricWhitelist,omitempty"`
+	MetricBlacklist            []string                   `json:"metricBlacklist,omitempty"`
+	MetricLabelsAllowlist      map[string][]string        `json:"metricLabelsAllowlist,omitempty"`
+	MetricAnnotationsAllowlist map[string][]string        `json:"metricAnnotationsAllowlist,omitempty"`
+	ResyncPeriod               *v1meta.Duration           `json:"resyncPeriod,omitempty"`
+	ResyncPeriods              map[string]v1meta.Duration `json:"resyncPeriods,omitempty"`
+}
+
+/* loadConfig returns a copy of the options with the settings of the config file applied */
+func loadConfig(path string, flags *Options) (*Options, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	/* unknown keys are an error, a typo must not reload with the flag defaults */
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	var c fileConfig
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	o := *flags
+	if c.Collectors != nil {
+		o.Collectors = collectorSet{}
+		for _, col := range c.Collectors {
+			if !collectorExists(col) {
+				return nil, fmt.Errorf("collector %q does not exist", col)
+			}
+			o.Collectors[col] = struct{}{}
+		}
+	}
+	if c.Namespaces != nil {
+		o.Namespace = v1meta.NamespaceAll
+		o.Namespaces = c.Namespaces
+	}
+	if c.NamespaceSelector != nil {
+		o.NamespaceSelector = *c.NamespaceSelector
+	}
+	if c.NamespaceExcludeRegex != nil {
+		o.NamespaceExcludeRegex = *c.NamespaceExcludeRegex
+	}
+	if c.MetricWhitelist != nil || c.MetricBlacklist != nil {
+		/* each entry is one pattern, unlike the flag it may contain commas */
+		o.MetricWhitelist = MetricSet{}
+		o.MetricBlacklist = MetricSet{}
+		for _, p := range c.MetricWhitelist {
+			if err := o.MetricWhitelist.add(p); err != nil {
+				return nil, fmt.Errorf("metricWhitelist: %v", err)
+			}
+		}
+		for _, p := range c.MetricBlacklist {
+			if err := o.MetricBlacklist.add(p); err != nil {
+				return nil, fmt.Errorf("metricBlacklist: %v", err)
+			}
+		}
+	}
+	if c.MetricLabelsAllowlist != nil {
+		o.MetricLabelsAllowlist = labelsAllowList{}
+		for col, keys := range c.MetricLabelsAllowlist {
+			if err := o.MetricLabelsAllowlist.add(col, keys); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.MetricAnnotationsAllowlist != nil {
+		o.MetricAnnotationsAllowlist = labelsAllowList{}
+		for col, keys := range c.MetricAnnotationsAllowlist {
+			if err := o.MetricAnnotationsAllowlist.add(col, keys); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.ResyncPeriod != nil {
+		if c.ResyncPeriod.Duration < 0 {
+			return nil, fmt.Errorf("resyncPeriod must not be negative")
+		}
+		o.ResyncPeriod = c.ResyncPeriod.Duration
+	}
+	if c.ResyncPeriods != nil {
+		o.ResyncPeriods = resyncPeriodMap{}
+		for col, d := range c.ResyncPeriods {
+			if !collectorExists(col) {
+				return nil, fmt.Errorf("collector %q of resyncPeriods does not exist", col)
+			}
+			if d.Duration < 0 {
+				return nil, fmt.Errorf("resync period of collector %s must not be negative", col)
+			}
+			o.ResyncPeriods[col] = d.Duration
+		}
+	}
+	return &o, nil
+}
+
+/* collectorGeneration: the collectors registered with one configuration, replaced on reload.
+  The Register functions take their namespaces, resync periods and health from it. */
+type collectorGeneration struct {
+	registry *prometheus.Registry
+	cancel   context.CancelFunc
+
+	namespaces    *namespaceSelection
+	health        *collectorHealth
+	defaultResync time.Duration
+	resyncPeriods resyncPeriodMap
+
+	/* applied when the generation is served */
+	labelsAllowlist      labelsAllowList
+	annotationsAllowlist labelsAllowList
+	enabled              collectorSet
+	reasons              map[string]string
+}
+
+/* resyncPeriod of the informers of the collector, per collector or the default */
+func (gen *collectorGeneration) resyncPeriod(collector string) time.Duration {
+	if d, ok := gen.resyncPeriods[collector]; ok {
+		return d
+	}
+	return gen.defaultResync
+}
+
+/* activate serves the generation: its registry, health and the settings used at collection */
+func (gen *collectorGeneration) activate(gatherer *swappableGatherer) {
+	setAllowlists(gen.labelsAllowlist, gen.annotationsAllowlist)
+	setCollectorEnabledMetric(gen.enabled, gen.reasons)
+	collectorsHealth.set(gen.health)
+	gatherer.set(gen.registry)
+}
+
+/* startCollectors creates a fresh registry and registers the configured collectors, their informers
+  run until the generation is cancelled. Global state is only changed by activate. */
+func startCollectors(ctx context.Context, opts *Options, kubeClient kubeclientset.Interface, kubeConfig *rest.Config) (*collectorGeneration, error) {
+	whiteBlackList, err := NewWhiteBlackList(opts.MetricWhitelist, opts.MetricBlacklist)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("metric white-blacklisting: %v", whiteBlackList.Status())
+
+	namespaces, err := newNamespaceSelection(append([]string{opts.Namespace}, opts.Namespaces...), opts.NamespaceSelector, opts.NamespaceExcludeRegex)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Using %s", namespaces)
+
+	var collectors collectorSet
+	if len(opts.Collectors) == 0 {
+		glog.Info("Using default collectors")
+		collectors = defaultCollectorsOApi
+	} else {
+		collectors = opts.Collectors
+	}
+
+	genCtx, cancel := context.WithCancel(ctx)
+	gen := &collectorGeneration{
+		registry:             prometheus.NewRegistry(),
+		cancel:               cancel,
+		namespaces:           namespaces,
+		health:               newCollectorHealth(),
+		defaultResync:        opts.ResyncPeriod,
+		resyncPeriods:        opts.ResyncPeriods,
+		labelsAllowlist:      opts.MetricLabelsAllowlist,
+		annotationsAllowlist: opts.MetricAnnotationsAllowlist,
+	}
+	/* collectors of API groups not served by the cluster, e.g. on OpenShift 3.x vs 4.x, are skipped */
+	gen.enabled, gen.reasons = availableCollectorSet(kubeConfig, collectors)
+
+	if err := namespaces.watchSelector(genCtx, kubeConfig, gen); err != nil {
+		cancel()
+		return nil, err
+	}
+	filteredRegistry := newFilteredRegisterer(gen.registry, whiteBlackList)
+	if err := registerCollectorsOApi(genCtx, filteredRegistry, kubeConfig, gen); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := registerCollectors(genCtx, filteredRegistry, kubeClient, gen); err != nil {
+		cancel()
+		return nil, err
+	}
+	return gen, nil
+}
+
+/* swappableGatherer: serves the registry of the current generation */
+type swappableGatherer struct {
+	mu       sync.RWMutex
+	gatherer prometheus.Gatherer
+}
+
+func (g *swappableGatherer) set(gatherer prometheus.Gatherer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gatherer = gatherer
+}
+
+func (g *swappableGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.mu.RLock()
+	gatherer := g.gatherer
+	g.mu.RUnlock()
+	return gatherer.Gather()
+}
+
+/* configReloader: re-registers the collectors into a fresh registry on SIGHUP or change of the --config file */
+type configReloader struct {
+	flags      *Options
+	kubeClient kubeclientset.Interface
+	kubeConfig *rest.Config
+	gatherer   *swappableGatherer
+
+	current *collectorGeneration
+	stamp   string
+}
+
+func configFileStamp(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "missing"
+	}
+	return fmt.Sprintf("%d:%d", fi.ModTime().UnixNano(), fi.Size())
+}
+
+/* reload: on errors the previous generation keeps running, the file is retried on the next check */
+func (r *configReloader) reload(ctx context.Context) error {
+	stamp := configFileStamp(r.flags.ConfigFile)
+	opts, err := loadConfig(r.flags.ConfigFile, r.flags)
+	if err != nil {
+		return err
+	}
+
+	gen, err := startCollectors(ctx, opts, r.kubeClient, r.kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	/* keep serving the previous generation, including its health for /readyz, until the new informers have synced */
+	if r.current != nil {
+		err := wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+			return gen.health.synced(), nil
+		})
+		if err != nil {
+			glog.Warningf("Informers not synced after reload, switching anyway")
+		}
+	}
+
+	gen.activate(r.gatherer)
+	if r.current != nil {
+		r.current.cancel()
+	}
+	r.current = gen
+	r.stamp = stamp
+	return nil
+}
+
+/* run reloads on SIGHUP and, if interval > 0, when the file changed */
+func (r *configReloader) run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			glog.Infof("Received SIGHUP, reloading %s", r.flags.ConfigFile)
+		case <-tick:
+			if configFileStamp(r.flags.ConfigFile) == r.stamp {
+				continue
+			}
+			glog.Infof("%s changed, reloading", r.flags.ConfigFile)
+		}
+
+		if err := r.reload(ctx); err != nil {
+			glog.Errorf("Failed to reload %s, keeping the previous configuration: %v", r.flags.ConfigFile, err)
+			ConfigLastReloadSuccessMetric.Set(0)
+			continue
+		}
+		ConfigLastReloadSuccessMetric.Set(1)
+		ConfigLastReloadSuccessTimestampMetric.SetToCurrentTime()
+	}
+}
@@ -18,15 +18,15 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
-	/*"k8s.io/api/core/v1"*/
+	"k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
-	"k8s.io/apimachinery/pkg/fields"
 	/*"k8s.io/client-go/kubernetes"*/
-	"k8s.io/client-go/tools/cache"
 
 	deploymentconfigv1meta "github.com/openshift/api/apps/v1" 
 	deploymentconfigv1clientset "github.com/openshift/client-go/apps/clientset/versioned"
@@ -48,6 +48,17 @@ var (
 	descDeploymentConfigLabelsHelp          = "DeploymentConfig labels converted to Prometheus labels."
 	descDeploymentConfigLabelsDefaultLabels = []string{"namespace", "deploymentconfig"}
 
+	descDeploymentConfigAnnotationsName          = "oapi_deploymentconfig_annotations"
+	descDeploymentConfigAnnotationsHelp          = "DeploymentConfig annotations converted to Prometheus labels."
+	descDeploymentConfigAnnotationsDefaultLabels = []string{"namespace", "deploymentconfig"}
+
+	deploymentConfigConditionTypes = []deploymentconfigv1meta.DeploymentConditionType{
+		deploymentconfigv1meta.DeploymentAvailable,
+		deploymentconfigv1meta.DeploymentProgressing,
+		deploymentconfigv1meta.DeploymentReplicaFailure,
+	}
+	conditionStatuses = []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown}
+
 	descDeploymentConfigCreated = prometheus.NewDesc(
 		"oapi_deploymentconfig_created",
 		"Unix creation timestamp of DeploymentConfig",
@@ -75,6 +86,36 @@ var (
 		[]string{"namespace", "deploymentconfig"}, nil,
 	)
 
+	descDeploymentConfigStatusReplicasReady = prometheus.NewDesc(
+		"oapi_deploymentconfig_status_replicas_ready",
+		"The number of ready replicas per DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig"}, nil,
+	)
+
+	descDeploymentConfigStatusLatestVersion = prometheus.NewDesc(
+		"oapi_deploymentconfig_status_latest_version",
+		"The version of the latest deployment of the DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig"}, nil,
+	)
+
+	descDeploymentConfigStatusCondition = prometheus.NewDesc(
+		"oapi_deploymentconfig_status_condition",
+		"The current status conditions of a DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig", "condition", "status"}, nil,
+	)
+
+	descDeploymentConfigStatusConditionReason = prometheus.NewDesc(
+		"oapi_deploymentconfig_status_condition_reason",
+		"The reason for the last transition of a status condition of a DeploymentConfig, e.g. ProgressDeadlineExceeded.",
+		[]string{"namespace", "deploymentconfig", "condition", "reason"}, nil,
+	)
+
+	descDeploymentConfigStatusConditionLastTransitionTime = prometheus.NewDesc(
+		"oapi_deploymentconfig_status_condition_last_transition_time",
+		"Unix timestamp of the last transition of a status condition of a DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig", "condition"}, nil,
+	)
+
 	descDeploymentConfigStatusObservedGeneration = prometheus.NewDesc(
 		"oapi_deploymentconfig_status_observed_generation",
 		"The generation observed by the deployment replication controller.",
@@ -105,6 +146,54 @@ var (
 		[]string{"namespace", "deploymentconfig"}, nil,
 	)
 
+	descDeploymentConfigStrategyType = prometheus.NewDesc(
+		"oapi_deploymentconfig_spec_strategy_type",
+		"The deployment strategy type (Rolling, Recreate, Custom) of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "strategy"}, nil,
+	)
+
+	descDeploymentConfigStrategyTimeoutSeconds = prometheus.NewDesc(
+		"oapi_deploymentconfig_spec_strategy_timeout_seconds",
+		"The time to wait for a rolling or recreate deployment of a deployment config before giving up.",
+		[]string{"namespace", "deploymentconfig", "strategy"}, nil,
+	)
+
+	descDeploymentConfigStrategyLifecycleHook = prometheus.NewDesc(
+		"oapi_deploymentconfig_spec_strategy_lifecycle_hook",
+		"Whether a pre, mid or post lifecycle hook is defined for the deployment strategy of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "strategy", "hook"}, nil,
+	)
+
+	descDeploymentConfigTrigger = prometheus.NewDesc(
+		"oapi_deploymentconfig_spec_trigger",
+		"The triggers which cause a new deployment of a deployment config. For ImageChange triggers with the referenced imagestreamtag.",
+		[]string{"namespace", "deploymentconfig", "type", "imagestreamtag_namespace", "imagestreamtag", "automatic"}, nil,
+	)
+
+	descDeploymentConfigContainerResourceRequests = prometheus.NewDesc(
+		"oapi_deploymentconfig_container_resource_requests",
+		"The number of requested resources per container of the pod template of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "container", "resource"}, nil,
+	)
+
+	descDeploymentConfigContainerResourceLimits = prometheus.NewDesc(
+		"oapi_deploymentconfig_container_resource_limits",
+		"The resource limits per container of the pod template of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "container", "resource"}, nil,
+	)
+
+	descDeploymentConfigDesiredResourceRequests = prometheus.NewDesc(
+		"oapi_deploymentconfig_desired_resource_requests",
+		"The requested resources of all containers multiplied by the desired replicas of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "resource"}, nil,
+	)
+
+	descDeploymentConfigDesiredResourceLimits = prometheus.NewDesc(
+		"oapi_deploymentconfig_desired_resource_limits",
+		"The resource limits of all containers multiplied by the desired replicas of a deployment config.",
+		[]string{"namespace", "deploymentconfig", "resource"}, nil,
+	)
+
 	descDeploymentConfigMetadataGeneration = prometheus.NewDesc(
 		"oapi_deploymentconfig_metadata_generation",
 		"Sequence number representing a specific generation of the desired state.",
@@ -116,6 +205,12 @@ var (
 		descDeploymentConfigLabelsHelp,
 		descDeploymentConfigLabelsDefaultLabels, nil,
 	)
+
+	descDeploymentConfigAnnotations = prometheus.NewDesc(
+		descDeploymentConfigAnnotationsName,
+		descDeploymentConfigAnnotationsHelp,
+		descDeploymentConfigAnnotationsDefaultLabels, nil,
+	)
 )
 
 
@@ -125,31 +220,32 @@ func (l DeploymentConfigLister) List() ([]deploymentconfigv1meta.DeploymentConfi
 	return l()
 }
 
-func RegisterDeploymentConfigCollectorOApi(registry prometheus.Registerer, kubeConfig *rest.Config, namespace string) {
+func RegisterDeploymentConfigCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
 	/* NOTE: appliedclusterresourcequata does not support watch and select by all namespaces*/
 
  /* Note: OAPI only provides very specifiy clientsets */
    deploymentconfigClient, err := deploymentconfigv1clientset.NewForConfig(kubeConfig)
    if err != nil {
-	   glog.Fatalf("Failed to access deploymentconfigs api: %v", err)
+	   return fmt.Errorf("failed to access deploymentconfigs api: %v", err)
    }
 
-   resyncPeriod, _ := time.ParseDuration("0h0m30s")
-
    client := deploymentconfigClient.AppsV1().RESTClient()
 
-	rqlw := cache.NewListWatchFromClient(client, "deploymentconfigs", namespace, fields.Everything())
-	rqinf := cache.NewSharedInformer(rqlw, &deploymentconfigv1meta.DeploymentConfig{}, resyncPeriod)
+	rqinf := newNamespacedInformer(client, "deploymentconfigs", &deploymentconfigv1meta.DeploymentConfig{}, gen)
 
 	deploymentConfigLister := DeploymentConfigLister(func() (deploymentconfigs []deploymentconfigv1meta.DeploymentConfig, err error) {
-		for _, dc := range rqinf.GetStore().List() {
+		for _, dc := range rqinf.List() {
 			deploymentconfigs = append(deploymentconfigs, *(dc.(*deploymentconfigv1meta.DeploymentConfig)))
 		}
 		return deploymentconfigs, nil
 	})
 
-	registry.MustRegister(&deploymentConfigCollector{store: deploymentConfigLister})
-	go rqinf.Run(context.Background().Done())
+	if err := registry.Register(&deploymentConfigCollector{store: deploymentConfigLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("deploymentconfigs", rqinf.HasSynced)
+	go rqinf.Run(ctx.Done())
+	return nil
 }
 
 type deploymentConfigStore interface {
@@ -169,13 +265,27 @@ func (dc *deploymentConfigCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- descDeploymentConfigStatusReplicasAvailable
 	ch <- descDeploymentConfigStatusReplicasUnavailable
 	ch <- descDeploymentConfigStatusReplicasUpdated
+	ch <- descDeploymentConfigStatusReplicasReady
+	ch <- descDeploymentConfigStatusLatestVersion
+	ch <- descDeploymentConfigStatusCondition
+	ch <- descDeploymentConfigStatusConditionReason
+	ch <- descDeploymentConfigStatusConditionLastTransitionTime
 	ch <- descDeploymentConfigStatusObservedGeneration
 	ch <- descDeploymentConfigSpecPaused
 	ch <- descDeploymentConfigStrategyRollingUpdateMaxUnavailable
 	ch <- descDeploymentStrategyRollingUpdateMaxSurge
 	ch <- descDeploymentConfigSpecReplicas
+	ch <- descDeploymentConfigStrategyType
+	ch <- descDeploymentConfigStrategyTimeoutSeconds
+	ch <- descDeploymentConfigStrategyLifecycleHook
+	ch <- descDeploymentConfigTrigger
+	ch <- descDeploymentConfigContainerResourceRequests
+	ch <- descDeploymentConfigContainerResourceLimits
+	ch <- descDeploymentConfigDesiredResourceRequests
+	ch <- descDeploymentConfigDesiredResourceLimits
 	ch <- descDeploymentConfigMetadataGeneration
 	ch <- descDeploymentConfigLabels
+	ch <- descDeploymentConfigAnnotations
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -211,6 +321,15 @@ func deploymentLabelsDesc(labelKeys []string) *prometheus.Desc {
 	)
 }
 
+func deploymentAnnotationsDesc(annotationKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descDeploymentConfigAnnotationsName,
+		descDeploymentConfigAnnotationsHelp,
+		append(descDeploymentConfigAnnotationsDefaultLabels, annotationKeys...),
+		nil,
+	)
+}
+
 
 
 func (dc *deploymentConfigCollector) collectDeploymentConfig(ch chan<- prometheus.Metric, d deploymentconfigv1meta.DeploymentConfig) {
@@ -218,8 +337,10 @@ func (dc *deploymentConfigCollector) collectDeploymentConfig(ch chan<- prometheu
 		lv = append([]string{d.Namespace, d.Name}, lv...)
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
 	}
-	labelKeys, labelValues := kubeLabelsToPrometheusLabels(d.Labels)
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("deploymentconfigs", d.Labels)
 	addGauge(deploymentLabelsDesc(labelKeys), 1, labelValues...)
+	annotationKeys, annotationValues := kubeAnnotationsToPrometheusLabels("deploymentconfigs", d.Annotations)
+	addGauge(deploymentAnnotationsDesc(annotationKeys), 1, annotationValues...)
 	if !d.CreationTimestamp.IsZero() {
 		addGauge(descDeploymentConfigCreated, float64(d.CreationTimestamp.Unix()))
 	}
@@ -227,21 +348,61 @@ func (dc *deploymentConfigCollector) collectDeploymentConfig(ch chan<- prometheu
 	addGauge(descDeploymentConfigStatusReplicasAvailable, float64(d.Status.AvailableReplicas))
 	addGauge(descDeploymentConfigStatusReplicasUnavailable, float64(d.Status.UnavailableReplicas))
 	addGauge(descDeploymentConfigStatusReplicasUpdated, float64(d.Status.UpdatedReplicas))
+	addGauge(descDeploymentConfigStatusReplicasReady, float64(d.Status.ReadyReplicas))
+	addGauge(descDeploymentConfigStatusLatestVersion, float64(d.Status.LatestVersion))
 	addGauge(descDeploymentConfigStatusObservedGeneration, float64(d.Status.ObservedGeneration))
 	addGauge(descDeploymentConfigSpecPaused, boolFloat64(d.Spec.Paused))
 	addGauge(descDeploymentConfigSpecReplicas, float64(d.Spec.Replicas))
 	addGauge(descDeploymentConfigMetadataGeneration, float64(d.ObjectMeta.Generation))
 
+	for _, c := range d.Status.Conditions {
+		known := false
+		for _, t := range deploymentConfigConditionTypes {
+			known = known || c.Type == t
+		}
+		if !known {
+			continue
+		}
+		for _, s := range conditionStatuses {
+			addGauge(descDeploymentConfigStatusCondition, boolFloat64(c.Status == s), string(c.Type), string(s))
+		}
+		if c.Reason != "" {
+			addGauge(descDeploymentConfigStatusConditionReason, 1, string(c.Type), c.Reason)
+		}
+		if !c.LastTransitionTime.IsZero() {
+			addGauge(descDeploymentConfigStatusConditionLastTransitionTime, float64(c.LastTransitionTime.Unix()), string(c.Type))
+		}
+	}
+
 	   
 
 	if (false) {
 		spew.Dump(d.Spec.Strategy)
 	}
 
+	strategy := string(d.Spec.Strategy.Type)
+	addGauge(descDeploymentConfigStrategyType, 1, strategy)
+
+	if (d.Spec.Strategy.RecreateParams != nil) {
+		dcStratParams := d.Spec.Strategy.RecreateParams
+		if dcStratParams.TimeoutSeconds != nil {
+			addGauge(descDeploymentConfigStrategyTimeoutSeconds, float64(*dcStratParams.TimeoutSeconds), strategy)
+		}
+		addGauge(descDeploymentConfigStrategyLifecycleHook, boolFloat64(dcStratParams.Pre != nil), strategy, "pre")
+		addGauge(descDeploymentConfigStrategyLifecycleHook, boolFloat64(dcStratParams.Mid != nil), strategy, "mid")
+		addGauge(descDeploymentConfigStrategyLifecycleHook, boolFloat64(dcStratParams.Post != nil), strategy, "post")
+	}
+
 	
 	if (d.Spec.Strategy.RollingParams != nil) {
 		dcStratParams := d.Spec.Strategy.RollingParams
 
+		if dcStratParams.TimeoutSeconds != nil {
+			addGauge(descDeploymentConfigStrategyTimeoutSeconds, float64(*dcStratParams.TimeoutSeconds), strategy)
+		}
+		addGauge(descDeploymentConfigStrategyLifecycleHook, boolFloat64(dcStratParams.Pre != nil), strategy, "pre")
+		addGauge(descDeploymentConfigStrategyLifecycleHook, boolFloat64(dcStratParams.Post != nil), strategy, "post")
+
 		maxUnavailable, err := intstr.GetValueFromIntOrPercent(dcStratParams.MaxUnavailable, int(d.Spec.Replicas), true)
 		if err != nil {
 			glog.Errorf("Error converting RollingUpdate MaxSurge to int: %s", err)
@@ -257,6 +418,47 @@ func (dc *deploymentConfigCollector) collectDeploymentConfig(ch chan<- prometheu
 	   }
 	}
 
+	/* avoid duplicate metrics for triggers defined several times */
+	triggers := make(map[[4]string]struct{})
+	for _, t := range d.Spec.Triggers {
+		trigger := [4]string{string(t.Type), "", "", ""}
+		if t.ImageChangeParams != nil {
+			from := t.ImageChangeParams.From
+			fromNamespace := from.Namespace
+			if fromNamespace == "" {
+				fromNamespace = d.Namespace
+			}
+			trigger = [4]string{string(t.Type), fromNamespace, from.Name, strconv.FormatBool(t.ImageChangeParams.Automatic)}
+		}
+		triggers[trigger] = struct{}{}
+	}
+	for t := range triggers {
+		addGauge(descDeploymentConfigTrigger, 1, t[0], t[1], t[2], t[3])
+	}
+
+	if d.Spec.Template != nil {
+		desiredRequests := make(map[string]float64)
+		desiredLimits := make(map[string]float64)
+		for _, c := range d.Spec.Template.Spec.Containers {
+			for res, qty := range c.Resources.Requests {
+				v := float64(qty.MilliValue())/1000
+				addGauge(descDeploymentConfigContainerResourceRequests, v, c.Name, string(res))
+				desiredRequests[string(res)] += v * float64(d.Spec.Replicas)
+			}
+			for res, qty := range c.Resources.Limits {
+				v := float64(qty.MilliValue())/1000
+				addGauge(descDeploymentConfigContainerResourceLimits, v, c.Name, string(res))
+				desiredLimits[string(res)] += v * float64(d.Spec.Replicas)
+			}
+		}
+		for res, v := range desiredRequests {
+			addGauge(descDeploymentConfigDesiredResourceRequests, v, res)
+		}
+		for res, v := range desiredLimits {
+			addGauge(descDeploymentConfigDesiredResourceLimits, v, res)
+		}
+	}
+
 }
 
 
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+/* reasons of oapi_collector_enabled */
+const (
+	collectorReasonAvailable       = "available"
+	collectorReasonNotSelected     = "not_selected"
+	collectorReasonNotServed       = "api_not_served"
+	collectorReasonLegacyOApiOnly  = "legacy_oapi_only"
+	collectorReasonDiscoveryFailed = "discovery_failed"
+)
+
+var (
+	/* API group, version and resource read by each collector */
+	collectorResources = map[string]schema.GroupVersionResource{
+		"appliedclusterresourcequotas": {Group: "quota.openshift.io", Version: "v1", Resource: "appliedclusterresourcequotas"},
+		"buildconfigs":                 {Group: "build.openshift.io", Version: "v1", Resource: "buildconfigs"},
+		"builds":                       {Group: "build.openshift.io", Version: "v1", Resource: "builds"},
+		"clusterresourcequotas":        {Group: "quota.openshift.io", Version: "v1", Resource: "clusterresourcequotas"},
+		"deploymentconfigs":            {Group: "apps.openshift.io", Version: "v1", Resource: "deploymentconfigs"},
+		"imagestreams":                 {Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"},
+		"projects":                     {Group: "project.openshift.io", Version: "v1", Resource: "projects"},
+		"routes":                       {Group: "route.openshift.io", Version: "v1", Resource: "routes"},
+		"limitranges":                  {Version: "v1", Resource: "limitranges"},
+		"replicationcontrollers":       {Version: "v1", Resource: "replicationcontrollers"},
+		"resourcequotas":               {Version: "v1", Resource: "resourcequotas"},
+	}
+
+	CollectorEnabledMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oapi_collector_enabled",
+			Help: "Whether the collector is enabled, the reason tells why a collector is skipped.",
+		},
+		[]string{"collector", "reason"},
+	)
+)
+
+/* apiDiscovery: resources served per group version, read once per configuration */
+type apiDiscovery struct {
+	client    discovery.DiscoveryInterface
+	resources map[string]map[string]bool
+	legacy    map[string]bool
+}
+
+func newAPIDiscovery(kubeConfig *rest.Config) (*apiDiscovery, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %v", err)
+	}
+	return &apiDiscovery{client: client, resources: make(map[string]map[string]bool)}, nil
+}
+
+/* served returns whether the resource is served, a group version not found is no error */
+func (d *apiDiscovery) served(gvr schema.GroupVersionResource) (bool, error) {
+	gv := gvr.GroupVersion().String()
+	resources, ok := d.resources[gv]
+	if !ok {
+		list, err := d.client.ServerResourcesForGroupVersion(gv)
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		resources = make(map[string]bool)
+		if list != nil {
+			for _, r := range list.APIResources {
+				resources[r.Name] = true
+			}
+		}
+		d.resources[gv] = resources
+	}
+	return resources[gvr.Resource], nil
+}
+
+/* servedLegacy returns whether the resource is served by the pre 3.6 /oapi/v1 endpoint,
+  which the collectors do not support */
+func (d *apiDiscovery) servedLegacy(resource string) bool {
+	if d.legacy == nil {
+		d.legacy = make(map[string]bool)
+		/* decoded by the client, the answer may be protobuf */
+		list := v1meta.APIResourceList{}
+		if err := d.client.RESTClient().Get().AbsPath("/oapi/v1").Do().Into(&list); err != nil {
+			if !errors.IsNotFound(err) {
+				glog.Warningf("API discovery of the legacy /oapi/v1 endpoint failed: %v", err)
+			}
+			return false
+		}
+		for _, r := range list.APIResources {
+			d.legacy[r.Name] = true
+		}
+	}
+	return d.legacy[resource]
+}
+
+/* availableCollectorSet returns the selected collectors whose API is served
+  and the reason of oapi_collector_enabled for all known collectors */
+func availableCollectorSet(kubeConfig *rest.Config, selected collectorSet) (collectorSet, map[string]string) {
+	reasons := make(map[string]string)
+	d, err := newAPIDiscovery(kubeConfig)
+	if err != nil {
+		glog.Warningf("API discovery failed, enabling the selected collectors: %v", err)
+	}
+	for col := range availableCollectors {
+		reasons[col] = collectorReasonNotSelected
+	}
+	for col := range availableCollectorsOApi {
+		reasons[col] = collectorReasonNotSelected
+	}
+
+	enabled := collectorSet{}
+	for col := range selected {
+		if d == nil {
+			reasons[col] = collectorReasonDiscoveryFailed
+			enabled[col] = struct{}{}
+			continue
+		}
+		gvr := collectorResources[col]
+		ok, err := d.served(gvr)
+		switch {
+		case err != nil:
+			glog.Warningf("API discovery of %s failed, enabling collector %s anyway: %v", gvr.GroupVersion(), col, err)
+			reasons[col] = collectorReasonDiscoveryFailed
+			enabled[col] = struct{}{}
+		case ok:
+			reasons[col] = collectorReasonAvailable
+			enabled[col] = struct{}{}
+		case gvr.Group != "" && d.servedLegacy(gvr.Resource):
+			glog.Warningf("Skipping collector %s: %s is only served by the legacy /oapi endpoint", col, gvr.Resource)
+			reasons[col] = collectorReasonLegacyOApiOnly
+		default:
+			glog.Warningf("Skipping collector %s: %s is not served by the API server", col, gvr)
+			reasons[col] = collectorReasonNotServed
+		}
+	}
+
+	return enabled, reasons
+}
+
+func setCollectorEnabledMetric(enabled collectorSet, reasons map[string]string) {
+	CollectorEnabledMetric.Reset()
+	for col, reason := range reasons {
+		_, ok := enabled[col]
+		v := 0.0
+		if ok {
+			v = 1
+		}
+		CollectorEnabledMetric.WithLabelValues(col, reason).Set(v)
+	}
+}
@@ -4,6 +4,7 @@ require (
 	github.com/Azure/go-autorest/autorest v0.2.0 // indirect
 	github.com/apache/thrift v0.12.0 // indirect
 	github.com/davecgh/go-spew v1.1.1
+	github.com/ghodss/yaml v1.0.0
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
 	github.com/gophercloud/gophercloud v0.2.0 // indirect
 	github.com/imdario/mergo v0.3.7 // indirect
@@ -11,7 +12,7 @@ require (
 	github.com/openshift/client-go v0.0.0-20180830153425-431ec9a26e50
 	github.com/openshift/origin v4.1.0+incompatible
 	github.com/openzipkin/zipkin-go v0.1.6 // indirect
-	github.com/prometheus/client_golang v1.0.0
+	github.com/prometheus/client_golang v1.5.0
 	github.com/spf13/pflag v1.0.3
 	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
@@ -0,0 +1,201 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+/* collectorsHealth: for /readyz and /healthz?verbose the health of the collector generation whose registry is served,
+  on reload it is switched together with the registry */
+var collectorsHealth = &servedHealth{health: newCollectorHealth()}
+
+type servedHealth struct {
+	mu     sync.RWMutex
+	health *collectorHealth
+}
+
+func (s *servedHealth) set(health *collectorHealth) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = health
+}
+
+func (s *servedHealth) get() *collectorHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.health
+}
+
+func (s *servedHealth) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	s.get().readyzHandler(w, r)
+}
+
+func (s *servedHealth) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	s.get().healthzHandler(w, r)
+}
+
+/* collectorHealth: sync state and last error of the collectors of one generation */
+
+type collectorHealth struct {
+	mu         sync.Mutex
+	collectors map[string]*collectorStatus
+}
+
+type collectorStatus struct {
+	synced []cache.InformerSynced
+	/* last error per source, e.g. the namespace of an informer, cleared when the source recovered */
+	errors map[string]collectorError
+}
+
+type collectorError struct {
+	message string
+	time    time.Time
+}
+
+/* collectorState is the JSON representation of a collector in /healthz?verbose */
+type collectorState struct {
+	Synced        bool       `json:"synced"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+}
+
+func newCollectorHealth() *collectorHealth {
+	return &collectorHealth{collectors: make(map[string]*collectorStatus)}
+}
+
+func (h *collectorHealth) status(collector string) *collectorStatus {
+	s, ok := h.collectors[collector]
+	if !ok {
+		s = &collectorStatus{errors: make(map[string]collectorError)}
+		h.collectors[collector] = s
+	}
+	return s
+}
+
+func (h *collectorHealth) synced() bool {
+	for _, state := range h.states() {
+		if !state.Synced {
+			return false
+		}
+	}
+	return true
+}
+
+// addInformer registers the HasSynced func of an informer started by the collector.
+func (h *collectorHealth) addInformer(collector string, synced cache.InformerSynced) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.status(collector)
+	s.synced = append(s.synced, synced)
+}
+
+// setError records the last error of the collector, a nil error is ignored.
+func (h *collectorHealth) setError(collector string, err error) {
+	if err == nil {
+		return
+	}
+	h.setSourceError(collector, "", err)
+}
+
+// setSourceError records the error of one source of the collector, a nil error clears it.
+func (h *collectorHealth) setSourceError(collector, source string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.status(collector)
+	if err == nil {
+		delete(s.errors, source)
+		return
+	}
+	s.errors[source] = collectorError{message: err.Error(), time: time.Now()}
+}
+
+/* states: the HasSynced funcs take the locks of the collectors, which may record errors while holding them,
+  so they are called after h.mu is released */
+func (h *collectorHealth) states() map[string]collectorState {
+	h.mu.Lock()
+	states := make(map[string]collectorState, len(h.collectors))
+	synced := make(map[string][]cache.InformerSynced, len(h.collectors))
+	for name, s := range h.collectors {
+		state := collectorState{}
+		/* the most recent error of all sources */
+		for _, e := range s.errors {
+			if state.LastErrorTime == nil || e.time.After(*state.LastErrorTime) {
+				t := e.time
+				state.LastError = e.message
+				state.LastErrorTime = &t
+			}
+		}
+		states[name] = state
+		synced[name] = append([]cache.InformerSynced(nil), s.synced...)
+	}
+	h.mu.Unlock()
+
+	for name, funcs := range synced {
+		state := states[name]
+		state.Synced = true
+		for _, f := range funcs {
+			if !f() {
+				state.Synced = false
+			}
+		}
+		states[name] = state
+	}
+	return states
+}
+
+/* readyzHandler: 503 until the informers of all collectors have synced */
+func (h *collectorHealth) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	unsynced := []string{}
+	for name, state := range h.states() {
+		if !state.Synced {
+			unsynced = append(unsynced, name)
+		}
+	}
+	if len(unsynced) > 0 {
+		sort.Strings(unsynced)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not synced: " + strings.Join(unsynced, ",")))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+/* healthzHandler: "ok", with ?verbose the state of each collector as JSON */
+func (h *collectorHealth) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	if _, verbose := r.URL.Query()["verbose"]; !verbose {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(struct {
+		Status     string                    `json:"status"`
+		Collectors map[string]collectorState `json:"collectors"`
+	}{"ok", h.states()})
+}
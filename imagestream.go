@@ -0,0 +1,244 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/rest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	imagev1meta "github.com/openshift/api/image/v1"
+)
+
+var (
+	descImageStreamLabelsName          = "oapi_imagestream_labels"
+	descImageStreamLabelsHelp          = "ImageStream labels converted to Prometheus labels."
+	descImageStreamLabelsDefaultLabels = []string{"namespace", "imagestream"}
+
+	descImageStreamCreated = prometheus.NewDesc(
+		"oapi_imagestream_created",
+		"Unix creation timestamp of ImageStream",
+		[]string{"namespace", "imagestream"}, nil,
+	)
+
+	descImageStreamMetadataGeneration = prometheus.NewDesc(
+		"oapi_imagestream_metadata_generation",
+		"Sequence number representing a specific generation of the desired state.",
+		[]string{"namespace", "imagestream"}, nil,
+	)
+
+	descImageStreamTags = prometheus.NewDesc(
+		"oapi_imagestream_tags",
+		"The number of tags of the ImageStream.",
+		[]string{"namespace", "imagestream"}, nil,
+	)
+
+	descImageStreamTagGeneration = prometheus.NewDesc(
+		"oapi_imagestream_tag_generation",
+		"The generation of the latest image of the ImageStreamTag.",
+		[]string{"namespace", "imagestream", "tag"}, nil,
+	)
+
+	descImageStreamTagImageCreated = prometheus.NewDesc(
+		"oapi_imagestream_tag_image_created",
+		"Unix timestamp when the newest image of the ImageStreamTag was created.",
+		[]string{"namespace", "imagestream", "tag"}, nil,
+	)
+
+	descImageStreamTagImportScheduled = prometheus.NewDesc(
+		"oapi_imagestream_tag_import_scheduled",
+		"Whether the ImageStreamTag is periodically imported from its source.",
+		[]string{"namespace", "imagestream", "tag"}, nil,
+	)
+
+	descImageStreamTagImportError = prometheus.NewDesc(
+		"oapi_imagestream_tag_import_error",
+		"Whether the last import of the ImageStreamTag failed, with the failure reason.",
+		[]string{"namespace", "imagestream", "tag", "reason"}, nil,
+	)
+
+	descImageStreamLabels = prometheus.NewDesc(
+		descImageStreamLabelsName,
+		descImageStreamLabelsHelp,
+		descImageStreamLabelsDefaultLabels, nil,
+	)
+)
+
+
+type ImageStreamLister func() ([]imagev1meta.ImageStream, error)
+
+func (l ImageStreamLister) List() ([]imagev1meta.ImageStream, error) {
+	return l()
+}
+
+func RegisterImageStreamCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
+
+	client, err := newImageV1RESTClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access imagestreams api: %v", err)
+	}
+
+	isinf := newNamespacedInformer(client, "imagestreams", &imagev1meta.ImageStream{}, gen)
+
+	imageStreamLister := ImageStreamLister(func() (imagestreams []imagev1meta.ImageStream, err error) {
+		for _, is := range isinf.List() {
+			imagestreams = append(imagestreams, *(is.(*imagev1meta.ImageStream)))
+		}
+		return imagestreams, nil
+	})
+
+	if err := registry.Register(&imageStreamCollector{store: imageStreamLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("imagestreams", isinf.HasSynced)
+	go isinf.Run(ctx.Done())
+	return nil
+}
+
+/* newImageV1RESTClient: create REST client for image.openshift.io/v1
+  Note: the image clientset of openshift/client-go does not match the used openshift/api version,
+  hence the client is set up the same way as the generated one, but with an own scheme */
+func newImageV1RESTClient(kubeConfig *rest.Config) (rest.Interface, error) {
+	scheme := runtime.NewScheme()
+	if err := imagev1meta.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	config := *kubeConfig
+	gv := imagev1meta.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+
+	return rest.RESTClientFor(&config)
+}
+
+type imageStreamStore interface {
+	List() ([]imagev1meta.ImageStream, error)
+}
+
+// imageStreamCollector collects metrics about all imagestreams and their tags in the cluster.
+type imageStreamCollector struct {
+	store imageStreamStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (isc *imageStreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descImageStreamCreated
+	ch <- descImageStreamMetadataGeneration
+	ch <- descImageStreamTags
+	ch <- descImageStreamTagGeneration
+	ch <- descImageStreamTagImageCreated
+	ch <- descImageStreamTagImportScheduled
+	ch <- descImageStreamTagImportError
+	ch <- descImageStreamLabels
+}
+
+// Collect implements the prometheus.Collector interface.
+func (isc *imageStreamCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	iss, err := isc.store.List()
+	if err != nil {
+		glog.Errorf("listing imagestreams failed: %s", err)
+		return
+	}
+
+	for _, is := range iss {
+		isc.collectImageStream(ch, is)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("imagestream").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "imagestream"}).Observe(float64(len(iss)))
+
+	glog.Infof("collected %d imagestreams", len(iss))
+}
+
+func imageStreamLabelsDesc(labelKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descImageStreamLabelsName,
+		descImageStreamLabelsHelp,
+		append(descImageStreamLabelsDefaultLabels, labelKeys...),
+		nil,
+	)
+}
+
+
+func (isc *imageStreamCollector) collectImageStream(ch chan<- prometheus.Metric, is imagev1meta.ImageStream) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{is.Namespace, is.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("imagestreams", is.Labels)
+	addGauge(imageStreamLabelsDesc(labelKeys), 1, labelValues...)
+	if !is.CreationTimestamp.IsZero() {
+		addGauge(descImageStreamCreated, float64(is.CreationTimestamp.Unix()))
+	}
+	addGauge(descImageStreamMetadataGeneration, float64(is.ObjectMeta.Generation))
+
+	/* tags may only exist in spec (not yet imported) or only in status (pushed images) */
+	tags := make(map[string]struct{})
+	specTags := make(map[string]imagev1meta.TagReference)
+	for _, t := range is.Spec.Tags {
+		specTags[t.Name] = t
+		tags[t.Name] = struct{}{}
+	}
+	statusTags := make(map[string]imagev1meta.NamedTagEventList)
+	for _, t := range is.Status.Tags {
+		statusTags[t.Tag] = t
+		tags[t.Tag] = struct{}{}
+	}
+	addGauge(descImageStreamTags, float64(len(tags)))
+
+	for tag := range tags {
+		if st, ok := specTags[tag]; ok {
+			addGauge(descImageStreamTagImportScheduled, boolFloat64(st.ImportPolicy.Scheduled), tag)
+		}
+
+		t, ok := statusTags[tag]
+		if !ok {
+			continue
+		}
+		/* the newest tag event is the first item */
+		if len(t.Items) > 0 {
+			addGauge(descImageStreamTagGeneration, float64(t.Items[0].Generation), tag)
+			if !t.Items[0].Created.IsZero() {
+				addGauge(descImageStreamTagImageCreated, float64(t.Items[0].Created.Unix()), tag)
+			}
+		}
+
+		importError, reason := false, ""
+		for _, c := range t.Conditions {
+			if c.Type == imagev1meta.ImportSuccess && c.Status == corev1.ConditionFalse {
+				importError, reason = true, c.Reason
+			}
+		}
+		addGauge(descImageStreamTagImportError, boolFloat64(importError), tag, reason)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/api/core/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+var (
+	descLimitRangeCreated = prometheus.NewDesc(
+		"oapi_limitrange_created",
+		"Unix creation timestamp of limitrange",
+		[]string{"limitrange", "namespace"}, nil,
+	)
+	descLimitRange = prometheus.NewDesc(
+		"oapi_limitrange",
+		"Information about limitrange.",
+		[]string{
+			"limitrange",
+			"namespace",
+			"resource",
+			"type",
+			"constraint",
+		}, nil,
+	)
+)
+
+
+type LimitRangeLister func() ([]v1.LimitRange, error)
+
+func (l LimitRangeLister) List() ([]v1.LimitRange, error) {
+	return l()
+}
+
+func RegisterLimitRangeCollector(ctx context.Context, registry prometheus.Registerer, kubeClient kubeclientset.Interface, gen *collectorGeneration) error {
+
+	client := kubeClient.CoreV1().RESTClient()
+
+	lrinf := newNamespacedInformer(client, "limitranges", &v1.LimitRange{}, gen)
+
+	limitRangeLister := LimitRangeLister(func() (limitranges []v1.LimitRange, err error) {
+		for _, lr := range lrinf.List() {
+			limitranges = append(limitranges, *(lr.(*v1.LimitRange)))
+		}
+		return limitranges, nil
+	})
+
+	if err := registry.Register(&limitRangeCollector{store: limitRangeLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("limitranges", lrinf.HasSynced)
+	go lrinf.Run(ctx.Done())
+	return nil
+}
+
+type limitRangeStore interface {
+	List() ([]v1.LimitRange, error)
+}
+
+// limitRangeCollector collects metrics about all limitranges in the cluster.
+type limitRangeCollector struct {
+	store limitRangeStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (lrc *limitRangeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descLimitRangeCreated
+	ch <- descLimitRange
+}
+
+// Collect implements the prometheus.Collector interface.
+func (lrc *limitRangeCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	lrs, err := lrc.store.List()
+	if err != nil {
+		glog.Errorf("listing limitranges failed: %s", err)
+		return
+	}
+
+	for _, lr := range lrs {
+		lrc.collectLimitRange(ch, lr)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("limitranges").Observe(duration.Seconds())
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "limitranges"}).Observe(float64(len(lrs)))
+
+	glog.Infof("collected %d limitranges", len(lrs))
+}
+
+
+func (lrc *limitRangeCollector) collectLimitRange(ch chan<- prometheus.Metric, lr v1.LimitRange) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{lr.Name, lr.Namespace}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	if !lr.CreationTimestamp.IsZero() {
+		addGauge(descLimitRangeCreated, float64(lr.CreationTimestamp.Unix()))
+	}
+
+	for _, item := range lr.Spec.Limits {
+		constraints := []struct {
+			name string
+			list v1.ResourceList
+		}{
+			{"min", item.Min},
+			{"max", item.Max},
+			{"default", item.Default},
+			{"defaultRequest", item.DefaultRequest},
+			{"maxLimitRequestRatio", item.MaxLimitRequestRatio},
+		}
+		for _, c := range constraints {
+			for res, qty := range c.list {
+				addGauge(descLimitRange, float64(qty.MilliValue())/1000, string(res), string(item.Type), c.name)
+			}
+		}
+	}
+}
@@ -27,14 +27,20 @@ package main
 		"net/http"
 		"net/http/pprof"
 		"os"
+		"os/signal"
 		"strings"
+		"sync"
+		"syscall"
+		"time"
 	
 		"github.com/golang/glog"
 		"github.com/openshift/origin/pkg/util/proc"
 		"github.com/prometheus/client_golang/prometheus"
 		"github.com/prometheus/client_golang/prometheus/promhttp"
+		"golang.org/x/net/context"
 		_ "k8s.io/client-go/plugin/pkg/client/auth"
 		"k8s.io/client-go/tools/clientcmd"
+		"k8s.io/client-go/util/flowcontrol"
 	
 		/*kcollectors "k8s.io/kube-state-metrics/pkg/collectors"*/
 		/*"k8s.io/kube-state-metrics/pkg/options"
@@ -44,7 +50,6 @@ package main
 		
 		"sort"
      	"k8s.io/client-go/rest"
-		metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
     	kubeclientset "k8s.io/client-go/kubernetes"
 	    /*clientset "github.com/openshift/client-go/quota/clientset/versioned"*/
 	    /*oapiclientset "github.com/openshift/client-go"*/
@@ -55,12 +60,19 @@ package main
 const (
 	metricsPath = "/metrics"
 	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
 )
 
+/* set via --shutdown-timeout */
+var serverShutdownTimeout time.Duration
+
 var (
 	defaultCollectors = collectorSet{
 	}
-	availableCollectors = map[string]func(registry prometheus.Registerer, kubeClient kubeclientset.Interface, namespace string){
+	availableCollectors = map[string]func(ctx context.Context, registry prometheus.Registerer, kubeClient kubeclientset.Interface, gen *collectorGeneration) error{
+		"limitranges": RegisterLimitRangeCollector,
+		"replicationcontrollers": RegisterReplicationControllerCollector,
+		"resourcequotas": RegisterResourceQuotaCollector,
 	}
 
 	defaultCollectorsOApi = collectorSet{
@@ -68,10 +80,15 @@ var (
 		"clusterresourcequotas":         struct{}{},
 		"deploymentconfigs":         struct{}{},
 	}
-	availableCollectorsOApi = map[string]func(registry prometheus.Registerer, kubeConfig *rest.Config, namespace string){
+	availableCollectorsOApi = map[string]func(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error{
 		"appliedclusterresourcequotas":         RegisterAppliedClusterResourceQuotaCollectorOApi,
+		"buildconfigs": RegisterBuildConfigCollectorOApi,
+		"builds": RegisterBuildCollectorOApi,
 		"clusterresourcequotas":         RegisterClusterResourceQuotaCollectorOApi,
 		"deploymentconfigs": RegisterDeploymentConfigCollectorOApi,
+		"imagestreams": RegisterImageStreamCollectorOApi,
+		"projects": RegisterProjectCollectorOApi,
+		"routes": RegisterRouteCollectorOApi,
 	}
 
 	ScrapeErrorTotalMetric = prometheus.NewCounterVec(
@@ -90,6 +107,25 @@ var (
 		[]string{"resource"},
 	)	
 
+	/* reported by collectors which query the API at scrape time, 0 if the last collection was incomplete */
+	descCollectorSuccess = prometheus.NewDesc(
+		"oapi_collector_success",
+		"Whether the last collection of the collector succeeded without API errors.",
+		[]string{"collector"}, nil,
+	)
+
+	descCollectorLastRefresh = prometheus.NewDesc(
+		"oapi_collector_last_refresh_timestamp_seconds",
+		"Unix timestamp of the last refresh of the data served by the collector.",
+		[]string{"collector"}, nil,
+	)
+
+	descCollectorStaleness = prometheus.NewDesc(
+		"oapi_collector_staleness_seconds",
+		"Age of the data served by the collector in seconds.",
+		[]string{"collector"}, nil,
+	)
+
 	ScrapeDurationHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "oapi_durations_per_scrape",
@@ -110,14 +146,18 @@ func (c *collectorSet) String() string {
 	return strings.Join(ss, ",")
 }
 
+func collectorExists(col string) bool {
+	_, ok1 := availableCollectors[col]
+	_, ok2 := availableCollectorsOApi[col]
+	return ok1 || ok2
+}
+
 func (c *collectorSet) Set(value string) error {
 	s := *c
 	cols := strings.Split(value, ",")
 	for _, col := range cols {
-		_, ok1 := availableCollectors[col]
-		_, ok2 := availableCollectorsOApi[col]
-		if !(ok1 || ok2) {
-			glog.Fatalf("Collector \"%s\" does not exist", col)
+		if !collectorExists(col) {
+			return fmt.Errorf("collector %q does not exist", col)
 		}
 		s[col] = struct{}{}
 	}
@@ -161,20 +201,6 @@ func main() {
 	}
 
 
-	var collectors collectorSet
-	if len(opts.Collectors) == 0 {
-		glog.Info("Using default collectors")
-		collectors = defaultCollectorsOApi
-	} else {
-		collectors = opts.Collectors
-	}
-
-	if opts.Namespace == metav1.NamespaceAll {
-		glog.Info("Using all namespace")
-	} else {
-		glog.Infof("Using %s namespace", opts.Namespace)
-	}
-
 	/*if isNotExists(opts.Kubeconfig)  {
 		glog.Fatalf("kubeconfig invalid and --in-cluster is false; kubeconfig must be set to a valid file(kubeconfig default file name: $HOME/.kube/config)")
 	}
@@ -185,13 +211,29 @@ func main() {
 
 	proc.StartReaper()
 
+	/* root context of the informers and servers, cancelled on SIGTERM or SIGINT */
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		glog.Infof("Received %s, shutting down", sig)
+		cancel()
+	}()
+	serverShutdownTimeout = opts.ShutdownTimeout
+
 	/*	kubeClientConfig, err := createOApiClient(opts.inCluster, opts.apiserver, opts.kubeconfig) */
-	kubeClient, err := createKubeClient(opts.Apiserver, opts.Kubeconfig)
+	if opts.KubeAPIQPS <= 0 || opts.KubeAPIBurst <= 0 {
+		glog.Fatalf("Error: --kube-api-qps and --kube-api-burst must be positive")
+	}
+	rateLimiter := newKubeAPIRateLimiter(opts.KubeAPIQPS, opts.KubeAPIBurst)
+	kubeClient, err := createKubeClient(opts.Apiserver, opts.Kubeconfig, rateLimiter)
 	if err != nil {
 		glog.Fatalf("Failed to create client: %v", err)
 	}
 
-	kubeClientConfig, err := createKubeConfig(opts.Apiserver, opts.Kubeconfig)
+	kubeClientConfig, err := createKubeConfig(opts.Apiserver, opts.Kubeconfig, rateLimiter)
 	if err != nil {
 		glog.Fatalf("Failed to create Kube Config: %v", err)
 	}
@@ -201,17 +243,81 @@ func main() {
 	telemetryMetricsRegistry.Register(ResourcesPerScrapeMetric)
 	telemetryMetricsRegistry.Register(ScrapeErrorTotalMetric)
 	telemetryMetricsRegistry.Register(ScrapeDurationHistogram)
+	telemetryMetricsRegistry.Register(KubeAPIRateLimiterWaitHistogram)
+	telemetryMetricsRegistry.Register(AppliedClusterResourceQuotaRequestDurationHistogram)
+	telemetryMetricsRegistry.Register(CollectorEnabledMetric)
+	if opts.ConfigFile != "" {
+		telemetryMetricsRegistry.Register(ConfigLastReloadSuccessMetric)
+		telemetryMetricsRegistry.Register(ConfigLastReloadSuccessTimestampMetric)
+	}
 	telemetryMetricsRegistry.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 	telemetryMetricsRegistry.Register(prometheus.NewGoCollector())
-	go telemetryServer(telemetryMetricsRegistry, opts.TelemetryHost, opts.TelemetryPort)
+	/* exposition format and compression negotiated with the scraping client */
+	handlerOpts := promhttp.HandlerOpts{
+		DisableCompression: !opts.EnableGZIPEncoding,
+		EnableOpenMetrics:  opts.EnableOpenMetrics,
+	}
 
+	var tlsFiles *tlsReloader
+	if opts.TLSCertFile != "" || opts.TLSPrivateKeyFile != "" || opts.TLSClientCAFile != "" {
+		tlsFiles, err = newTLSReloader(opts.TLSCertFile, opts.TLSPrivateKeyFile, opts.TLSClientCAFile)
+		if err != nil {
+			glog.Fatalf("Error: %s", err)
+		}
+		tlsFiles.watch(opts.TLSReloadInterval, ctx.Done())
+	}
+	var servers sync.WaitGroup
+	if opts.EnablePprof && opts.PprofAddress != "" {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			pprofServer(ctx, opts.PprofAddress)
+		}()
+	}
+	servers.Add(1)
+	go func() {
+		defer servers.Done()
+		telemetryServer(ctx, telemetryMetricsRegistry, opts.TelemetryHost, opts.TelemetryPort, handlerOpts, tlsFiles, opts.EnablePprof && opts.PprofAddress == "")
+	}()
+
+
+	appliedClusterResourceQuotaPollInterval = opts.AppliedClusterResourceQuotaPollInterval
+	appliedClusterResourceQuotaParallelism = opts.AppliedClusterResourceQuotaParallelism
+	appliedClusterResourceQuotaRequestTimeout = opts.AppliedClusterResourceQuotaRequestTimeout
+	appliedClusterResourceQuotaTimeout = opts.AppliedClusterResourceQuotaTimeout
+
+	/* collectors, namespaces, allowlists, metric filters and resync periods can be reloaded from the --config file */
+	registry := &swappableGatherer{}
+	reloader := &configReloader{flags: opts, kubeClient: kubeClient, kubeConfig: kubeClientConfig, gatherer: registry}
+	if opts.ConfigFile != "" {
+		if err := reloader.reload(ctx); err != nil {
+			glog.Fatalf("Error: %s", err)
+		}
+		ConfigLastReloadSuccessMetric.Set(1)
+		ConfigLastReloadSuccessTimestampMetric.SetToCurrentTime()
+		go reloader.run(ctx, opts.ConfigCheckInterval)
+	} else {
+		gen, err := startCollectors(ctx, opts, kubeClient, kubeClientConfig)
+		if err != nil {
+			glog.Fatalf("Error: %s", err)
+		}
+		gen.activate(registry)
+	}
 
-	registry := prometheus.NewRegistry()
-	registerCollectorsOApi(registry, kubeClientConfig, collectors, opts.Namespace)
-	registerCollectors(registry, kubeClient, collectors, opts.Namespace)
 
+	var metricsAuth *metricsAuthorizer
+	if opts.MetricsAuth {
+		metricsAuth, err = newMetricsAuthorizer(kubeClientConfig, opts.MetricsAuthVerb, opts.MetricsAuthPath, opts.MetricsAuthCacheTTL)
+		if err != nil {
+			glog.Fatalf("Error: %s", err)
+		}
+		glog.Infof("Metrics require %s on %s", opts.MetricsAuthVerb, opts.MetricsAuthPath)
+	}
 
-	metricsServer(registry, opts.Port)
+	metricsServer(ctx, registry, opts.Host, opts.Port, handlerOpts, tlsFiles, metricsAuth)
+	servers.Wait()
+	glog.Infof("Shutdown complete")
+	glog.Flush()
 }
 
 
@@ -227,11 +333,12 @@ func isNotExists(file string) bool {
 /* createKubeConfig: create rest.Config as base for creation clientsets
   Note: OAPI only provides very specifiy clientsets,
   the specify clients are created in the object collectors Register... method */
-func createKubeConfig(apiserver string, kubeconfig string) (config *rest.Config, err error) {
+func createKubeConfig(apiserver string, kubeconfig string, rateLimiter flowcontrol.RateLimiter) (config *rest.Config, err error) {
 	config, err = clientcmd.BuildConfigFromFlags(apiserver, kubeconfig)
 	if err != nil {
 		return nil, err
 	}
+	config.RateLimiter = rateLimiter
 
 	config.UserAgent = GetVersion().String()
 	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
@@ -259,11 +366,12 @@ func createKubeConfig(apiserver string, kubeconfig string) (config *rest.Config,
 }
 
 /*  createKubeClient: create generic client for Kubernetes API*/
-func createKubeClient(apiserver string, kubeconfig string) (kubeclientset.Interface, error) {
+func createKubeClient(apiserver string, kubeconfig string, rateLimiter flowcontrol.RateLimiter) (kubeclientset.Interface, error) {
 	config, err := clientcmd.BuildConfigFromFlags(apiserver, kubeconfig)
 	if err != nil {
 		return nil, err
 	}
+	config.RateLimiter = rateLimiter
 
 	config.UserAgent = GetVersion().String()
 	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
@@ -289,27 +397,24 @@ func createKubeClient(apiserver string, kubeconfig string) (kubeclientset.Interf
 	return kubeClient, nil
 }
 
-func metricsServer(registry prometheus.Gatherer, port int) {
+func metricsServer(ctx context.Context, registry prometheus.Gatherer, host string, port int, handlerOpts promhttp.HandlerOpts, tlsFiles *tlsReloader, metricsAuth *metricsAuthorizer) {
 	// Address to listen on for web interface and telemetry
-	listenAddress := fmt.Sprintf(":%d", port)
+	listenAddress := net.JoinHostPort(host, strconv.Itoa(port))
 
 	glog.Infof("Starting metrics server: %s", listenAddress)
 
 	mux := http.NewServeMux()
 
-	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
-	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
-	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
-
 	// Add metricsPath
-	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	var metricsHandler http.Handler = promhttp.HandlerFor(registry, handlerOpts)
+	if metricsAuth != nil {
+		metricsHandler = metricsAuth.handler(metricsHandler)
+	}
+	mux.Handle(metricsPath, metricsHandler)
 	// Add healthzPath
-	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(200)
-		w.Write([]byte("ok"))
-	})
+	mux.HandleFunc(healthzPath, collectorsHealth.healthzHandler)
+	// Add readyzPath
+	mux.HandleFunc(readyzPath, collectorsHealth.readyzHandler)
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
@@ -319,14 +424,17 @@ func metricsServer(registry prometheus.Gatherer, port int) {
 			 <ul>
              <li><a href='` + metricsPath + `'>metrics</a></li>
              <li><a href='` + healthzPath + `'>healthz</a></li>
+             <li><a href='` + readyzPath + `'>readyz</a></li>
 			 </ul>
              </body>
              </html>`))
 	})
-	log.Fatal(http.ListenAndServe(listenAddress, mux))
+	if err := serve(ctx, &http.Server{Addr: listenAddress, Handler: mux}, tlsFiles); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func telemetryServer(registry prometheus.Gatherer, host string, port int) {
+func telemetryServer(ctx context.Context, registry prometheus.Gatherer, host string, port int, handlerOpts promhttp.HandlerOpts, tlsFiles *tlsReloader, enablePprof bool) {
 	// Address to listen on for web interface and telemetry
 	listenAddress := net.JoinHostPort(host, strconv.Itoa(port))
 
@@ -335,7 +443,12 @@ func telemetryServer(registry prometheus.Gatherer, host string, port int) {
 	mux := http.NewServeMux()
 
 	// Add metricsPath
-	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: promLogger{}}))
+	if enablePprof {
+		registerPprof(mux)
+	}
+
+	handlerOpts.ErrorLog = promLogger{}
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, handlerOpts))
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
@@ -348,7 +461,49 @@ func telemetryServer(registry prometheus.Gatherer, host string, port int) {
              </body>
              </html>`))
 	})
-	log.Fatal(http.ListenAndServe(listenAddress, mux))
+	if err := serve(ctx, &http.Server{Addr: listenAddress, Handler: mux}, tlsFiles); err != nil {
+		log.Fatal(err)
+	}
+}
+
+/* registerPprof: profiling endpoints, not exposed on the metrics server to the scrapers */
+func registerPprof(mux *http.ServeMux) {
+	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+}
+
+func pprofServer(ctx context.Context, listenAddress string) {
+	glog.Infof("Starting pprof server: %s", listenAddress)
+
+	mux := http.NewServeMux()
+	registerPprof(mux)
+	if err := serve(ctx, &http.Server{Addr: listenAddress, Handler: mux}, nil); err != nil {
+		log.Fatal(err)
+	}
+}
+
+/* serve runs the server until ctx is done, then waits up to --shutdown-timeout for in-flight requests */
+func serve(ctx context.Context, server *http.Server, tlsFiles *tlsReloader) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- listenAndServe(server, tlsFiles)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		glog.Errorf("Shutdown of server %s: %v", server.Addr, err)
+	}
+	return nil
 }
 
 // promLogger implements promhttp.Logger
@@ -363,31 +518,37 @@ func (pl promLogger) Println(v ...interface{}) {
 // otherwise the data is collected on demand in the Collect method of the object collector
 // and initializes and registers metrics for collection.
 
-func registerCollectorsOApi(registry prometheus.Registerer, kubeConfig *rest.Config, enabledCollectors collectorSet, namespace string) {
+func registerCollectorsOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
 	activeCollectors := []string{}
-	for c, _ := range enabledCollectors {
+	for c, _ := range gen.enabled {
 		f, ok := availableCollectorsOApi[c]
 		if ok {
-			f(registry, kubeConfig, namespace)
+			if err := f(ctx, registry, kubeConfig, gen); err != nil {
+				return fmt.Errorf("collector %s: %v", c, err)
+			}
 			activeCollectors = append(activeCollectors, c)
 		}
 	}
 
 	glog.Infof("Active collectors: %s", strings.Join(activeCollectors, ","))
+	return nil
 }
 
 // registerCollectors creates and starts informers and initializes and
 // registers metrics for collection via Kubernetes API.
 
-func registerCollectors(registry prometheus.Registerer, kubeClient kubeclientset.Interface, enabledCollectors collectorSet, namespace string) {
+func registerCollectors(ctx context.Context, registry prometheus.Registerer, kubeClient kubeclientset.Interface, gen *collectorGeneration) error {
 	activeCollectors := []string{}
-	for c, _ := range enabledCollectors {
+	for c, _ := range gen.enabled {
 		f, ok := availableCollectors[c]
 		if ok {
-			f(registry, kubeClient, namespace)
+			if err := f(ctx, registry, kubeClient, gen); err != nil {
+				return fmt.Errorf("collector %s: %v", c, err)
+			}
 			activeCollectors = append(activeCollectors, c)
 		}
 	}
 
 	glog.Infof("Active collectors: %s", strings.Join(activeCollectors, ","))
+	return nil
 }
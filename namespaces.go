@@ -0,0 +1,330 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	projectv1meta "github.com/openshift/api/project/v1"
+	projectv1clientset "github.com/openshift/client-go/project/clientset/versioned"
+)
+
+/* namespaceSelection: namespaces the collectors are restricted to, set via
+  --namespace(s), --namespace-selector and --namespace-exclude-regex */
+type namespaceSelection struct {
+	/* explicitly listed namespaces, empty for all namespaces */
+	namespaces []string
+	exclude    *regexp.Regexp
+	selector   labels.Selector
+	/* projects matching the selector, watched via the project api so no cluster rights are needed */
+	selected cache.SharedInformer
+}
+
+func newNamespaceSelection(namespaces []string, selector string, excludeRegex string) (*namespaceSelection, error) {
+	s := &namespaceSelection{}
+
+	seen := make(map[string]struct{})
+	for _, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if _, ok := seen[ns]; ns == v1meta.NamespaceAll || ok {
+			continue
+		}
+		seen[ns] = struct{}{}
+		s.namespaces = append(s.namespaces, ns)
+	}
+	sort.Strings(s.namespaces)
+
+	if excludeRegex != "" {
+		re, err := regexp.Compile(excludeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace exclude regex %q: %v", excludeRegex, err)
+		}
+		s.exclude = re
+	}
+
+	if selector != "" {
+		sel, err := labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector %q: %v", selector, err)
+		}
+		s.selector = sel
+	}
+	return s, nil
+}
+
+/* watchSelector starts watching the projects matching the namespace selector */
+func (s *namespaceSelection) watchSelector(ctx context.Context, kubeConfig *rest.Config, gen *collectorGeneration) error {
+	if s.selector == nil {
+		return nil
+	}
+	projectClient, err := projectv1clientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access projects api: %v", err)
+	}
+
+	resyncPeriod := gen.resyncPeriod("namespace-selector")
+
+	selector := s.selector.String()
+	prlw := cache.NewFilteredListWatchFromClient(projectClient.ProjectV1().RESTClient(), "projects", "", func(options *v1meta.ListOptions) {
+		options.LabelSelector = selector
+	})
+	s.selected = cache.NewSharedInformer(prlw, &projectv1meta.Project{}, resyncPeriod)
+	gen.health.addInformer("namespace-selector", s.selected.HasSynced)
+	go s.selected.Run(ctx.Done())
+	return nil
+}
+
+/* isAll: no restriction at all */
+func (s *namespaceSelection) isAll() bool {
+	return len(s.namespaces) == 0 && s.exclude == nil && s.selector == nil
+}
+
+/* bySelector: the namespaces are only given by the selector, so they are taken from the
+  selected projects instead of watching or listing all namespaces, which needs cluster rights */
+func (s *namespaceSelection) bySelector() bool {
+	return len(s.namespaces) == 0 && s.selected != nil
+}
+
+/* informerNamespaces: namespaces to start informers for, NamespaceAll if not listed explicitly */
+func (s *namespaceSelection) informerNamespaces() []string {
+	if len(s.namespaces) == 0 {
+		return []string{v1meta.NamespaceAll}
+	}
+	return s.namespaces
+}
+
+/* selectedNamespaces: the namespaces of the projects matching the selector */
+func (s *namespaceSelection) selectedNamespaces() []string {
+	names := s.selected.GetStore().ListKeys()
+	sort.Strings(names)
+	return names
+}
+
+/* matches: whether metrics of the namespace are collected */
+func (s *namespaceSelection) matches(namespace string) bool {
+	if len(s.namespaces) > 0 {
+		i := sort.SearchStrings(s.namespaces, namespace)
+		if i == len(s.namespaces) || s.namespaces[i] != namespace {
+			return false
+		}
+	}
+	if s.exclude != nil && s.exclude.MatchString(namespace) {
+		return false
+	}
+	if s.selected != nil {
+		if _, ok, _ := s.selected.GetStore().GetByKey(namespace); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *namespaceSelection) String() string {
+	if s.isAll() {
+		return "all namespaces"
+	}
+	parts := []string{}
+	if len(s.namespaces) > 0 {
+		parts = append(parts, "namespaces "+strings.Join(s.namespaces, ","))
+	}
+	if s.selector != nil {
+		parts = append(parts, "selector "+s.selector.String())
+	}
+	if s.exclude != nil {
+		parts = append(parts, "excluding "+s.exclude.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+
+/* namespacedInformer: shared informers for a namespaced resource, one per selected namespace
+  or a single one for all namespaces, whose objects are filtered by the namespace selection.
+  With a namespace selector only, the informers follow the selected projects. */
+type namespacedInformer struct {
+	client       cache.Getter
+	resource     string
+	objType      runtime.Object
+	resyncPeriod time.Duration
+	namespaces   *namespaceSelection
+	health       *collectorHealth
+
+	mu        sync.Mutex
+	informers map[string]*namespaceInformer
+}
+
+type namespaceInformer struct {
+	informer cache.SharedInformer
+	stopCh   chan struct{}
+}
+
+/* newNamespacedInformer: the resource name is also the collector name for the resync period and health */
+func newNamespacedInformer(client cache.Getter, resource string, objType runtime.Object, gen *collectorGeneration) *namespacedInformer {
+	ni := &namespacedInformer{
+		client:       client,
+		resource:     resource,
+		objType:      objType,
+		resyncPeriod: gen.resyncPeriod(resource),
+		namespaces:   gen.namespaces,
+		health:       gen.health,
+		informers:    make(map[string]*namespaceInformer),
+	}
+	if !ni.namespaces.bySelector() {
+		for _, ns := range ni.namespaces.informerNamespaces() {
+			ni.informers[ns] = &namespaceInformer{informer: ni.newInformer(ns), stopCh: make(chan struct{})}
+		}
+	}
+	return ni
+}
+
+/* newInformer: list errors, e.g. a 403, are recorded for /healthz?verbose and cleared by the next successful list */
+func (ni *namespacedInformer) newInformer(ns string) cache.SharedInformer {
+	lw := cache.NewListWatchFromClient(ni.client, ni.resource, ns, fields.Everything())
+	list := lw.ListFunc
+	lw.ListFunc = func(options v1meta.ListOptions) (runtime.Object, error) {
+		obj, err := list(options)
+		if err != nil && ns != v1meta.NamespaceAll {
+			ni.health.setSourceError(ni.resource, ns, fmt.Errorf("namespace %s: %v", ns, err))
+		} else {
+			ni.health.setSourceError(ni.resource, ns, err)
+		}
+		return obj, err
+	}
+	return cache.NewSharedInformer(lw, ni.objType, ni.resyncPeriod)
+}
+
+func (ni *namespacedInformer) Run(stopCh <-chan struct{}) {
+	ni.mu.Lock()
+	for _, inf := range ni.informers {
+		go inf.informer.Run(stopCh)
+	}
+	ni.mu.Unlock()
+
+	if ni.namespaces.bySelector() {
+		ni.namespaces.selected.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				ni.add(obj, stopCh)
+			},
+			DeleteFunc: func(obj interface{}) {
+				ni.delete(obj)
+			},
+		})
+	}
+
+	<-stopCh
+	ni.mu.Lock()
+	for ns, inf := range ni.informers {
+		close(inf.stopCh)
+		delete(ni.informers, ns)
+	}
+	ni.mu.Unlock()
+}
+
+/* add starts an informer for the namespace of a project which got selected */
+func (ni *namespacedInformer) add(obj interface{}, stopCh <-chan struct{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil || !ni.namespaces.matches(key) {
+		return
+	}
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	select {
+	case <-stopCh:
+		return
+	default:
+	}
+	if _, ok := ni.informers[key]; ok {
+		return
+	}
+	inf := &namespaceInformer{informer: ni.newInformer(key), stopCh: make(chan struct{})}
+	ni.informers[key] = inf
+	go inf.informer.Run(inf.stopCh)
+	glog.V(2).Infof("Watching %s of namespace %s", ni.resource, key)
+}
+
+/* delete stops the informer of a project which is no longer selected */
+func (ni *namespacedInformer) delete(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	ni.mu.Lock()
+	inf, ok := ni.informers[key]
+	if ok {
+		close(inf.stopCh)
+		delete(ni.informers, key)
+	}
+	ni.mu.Unlock()
+	if ok {
+		/* not under ni.mu, the health takes its lock before HasSynced */
+		ni.health.setSourceError(ni.resource, key, nil)
+		glog.V(2).Infof("Stopped watching %s of namespace %s", ni.resource, key)
+	}
+}
+
+/* HasSynced: with a namespace selector only, also every selected project needs a synced informer,
+  they are started by the event handlers after the projects were listed */
+func (ni *namespacedInformer) HasSynced() bool {
+	var selected []string
+	if ni.namespaces.bySelector() {
+		if !ni.namespaces.selected.HasSynced() {
+			return false
+		}
+		selected = ni.namespaces.selectedNamespaces()
+	}
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	for _, ns := range selected {
+		if _, ok := ni.informers[ns]; !ok && ni.namespaces.matches(ns) {
+			return false
+		}
+	}
+	for _, inf := range ni.informers {
+		if !inf.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (ni *namespacedInformer) List() (objs []interface{}) {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	for _, inf := range ni.informers {
+		for _, obj := range inf.informer.GetStore().List() {
+			o, err := meta.Accessor(obj)
+			if err == nil && ni.namespaces.matches(o.GetNamespace()) {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs
+}
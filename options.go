@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -32,15 +33,42 @@ type Options struct {
 	Host                                 string
 	TelemetryPort                        int
 	TelemetryHost                        string
+	TLSCertFile                          string
+	TLSPrivateKeyFile                    string
+	TLSClientCAFile                      string
+	TLSReloadInterval                    time.Duration
+	ShutdownTimeout                      time.Duration
+	EnablePprof                          bool
+	PprofAddress                         string
+	MetricsAuth                          bool
+	MetricsAuthVerb                      string
+	MetricsAuthPath                      string
+	MetricsAuthCacheTTL                  time.Duration
 	Collectors                           collectorSet
+	ConfigFile                           string
+	ConfigCheckInterval                  time.Duration
 	Namespace                            string
-	//MetricBlacklist                      MetricSet
-	//MetricWhitelist                      MetricSet
+	Namespaces                           []string
+	NamespaceSelector                    string
+	NamespaceExcludeRegex                string
+	ResyncPeriod                         time.Duration
+	ResyncPeriods                        resyncPeriodMap
+	KubeAPIQPS                           float32
+	KubeAPIBurst                         int
+	AppliedClusterResourceQuotaPollInterval time.Duration
+	AppliedClusterResourceQuotaParallelism int
+	AppliedClusterResourceQuotaRequestTimeout time.Duration
+	AppliedClusterResourceQuotaTimeout time.Duration
+	MetricBlacklist                      MetricSet
+	MetricWhitelist                      MetricSet
+	MetricLabelsAllowlist                labelsAllowList
+	MetricAnnotationsAllowlist           labelsAllowList
 	Version                              bool
 	//DisablePodNonGenericResourceMetrics  bool
 	//DisableNodeNonGenericResourceMetrics bool
 
 	EnableGZIPEncoding bool
+	EnableOpenMetrics  bool
 
 	flags *pflag.FlagSet
 }
@@ -48,8 +76,12 @@ type Options struct {
 func NewOptions() *Options {
 	return &Options{
 		Collectors:      collectorSet{},
-		//MetricWhitelist: MetricSet{},
-		//MetricBlacklist: MetricSet{},
+		ResyncPeriod:    30 * time.Second,
+		ResyncPeriods:   resyncPeriodMap{},
+		MetricWhitelist: MetricSet{},
+		MetricBlacklist: MetricSet{},
+		MetricLabelsAllowlist:      labelsAllowList{},
+		MetricAnnotationsAllowlist: labelsAllowList{},
 	}
 }
 
@@ -73,14 +105,41 @@ func (o *Options) AddFlags() {
 	o.flags.StringVar(&o.Host, "host", "0.0.0.0", `Host to expose metrics on.`)
 	o.flags.IntVar(&o.TelemetryPort, "telemetry-port", 81, `Port to expose oapi-exporter self metrics on.`)
 	o.flags.StringVar(&o.TelemetryHost, "telemetry-host", "0.0.0.0", `Host to expose oapi-exporter self metrics on.`)
+	o.flags.StringVar(&o.TLSCertFile, "tls-cert-file", "", "File containing the x509 certificate for HTTPS of the metrics and telemetry servers. Defaults to plain HTTP.")
+	o.flags.StringVar(&o.TLSPrivateKeyFile, "tls-private-key-file", "", "File containing the x509 private key matching --tls-cert-file.")
+	o.flags.StringVar(&o.TLSClientCAFile, "tls-client-ca-file", "", "File containing the CA certificates to verify client certificates against (mTLS). Defaults to no client certificates.")
+	o.flags.DurationVar(&o.TLSReloadInterval, "tls-reload-interval", 10*time.Second, "Interval to check the TLS files for changes, e.g. a rotated serving certificate.")
+	o.flags.DurationVar(&o.ShutdownTimeout, "shutdown-timeout", 20*time.Second, "Time to finish in-flight scrapes on SIGTERM or SIGINT before exiting.")
+	o.flags.BoolVar(&o.EnablePprof, "enable-pprof", false, "Expose the Go profiling endpoints /debug/pprof/ on the telemetry server or --pprof-address.")
+	o.flags.StringVar(&o.PprofAddress, "pprof-address", "", "Separate address for the profiling endpoints, e.g. 127.0.0.1:6060 for local access only. Defaults to the telemetry server.")
+	o.flags.BoolVar(&o.MetricsAuth, "metrics-auth", false, "Require a bearer token on the metrics path, authenticated via TokenReview and authorized via SubjectAccessReview.")
+	o.flags.StringVar(&o.MetricsAuthVerb, "metrics-auth-verb", "get", "Verb on --metrics-auth-path the user of the bearer token needs to be allowed.")
+	o.flags.StringVar(&o.MetricsAuthPath, "metrics-auth-path", metricsPath, "Non-resource URL the user of the bearer token needs to be allowed --metrics-auth-verb on.")
+	o.flags.DurationVar(&o.MetricsAuthCacheTTL, "metrics-auth-cache-ttl", time.Minute, "Duration to cache the authentication and authorization decision per bearer token.")
+	o.flags.StringVar(&o.ConfigFile, "config", "", "YAML file with collectors, namespaces, label allowlists, metric filters and resync periods overriding the flags. Reloaded on SIGHUP or change.")
+	o.flags.DurationVar(&o.ConfigCheckInterval, "config-check-interval", 30*time.Second, "Interval to check the --config file for changes, 0 to reload on SIGHUP only.")
 	o.flags.Var(&o.Collectors, "collectors", fmt.Sprintf("Comma-separated list of collectors to be enabled. Defaults to %q", &defaultCollectors))
 	o.flags.StringVar(&o.Namespace, "namespace", "", fmt.Sprintf("Nnamespaces to be enabled. Defaults to all" ))
-	//o.flags.Var(&o.MetricWhitelist, "metric-whitelist", "Comma-separated list of metrics to be exposed. The whitelist and blacklist are mutually exclusive.")
-	//o.flags.Var(&o.MetricBlacklist, "metric-blacklist", "Comma-separated list of metrics not to be enabled. The whitelist and blacklist are mutually exclusive.")
+	o.flags.StringSliceVar(&o.Namespaces, "namespaces", []string{}, "Comma-separated list of namespaces to be enabled, combined with --namespace. Defaults to all")
+	o.flags.StringVar(&o.NamespaceSelector, "namespace-selector", "", "Label selector of the projects to be enabled, e.g. team=a. Only projects visible to the user are considered.")
+	o.flags.StringVar(&o.NamespaceExcludeRegex, "namespace-exclude-regex", "", "Regular expression of namespaces to be excluded, e.g. '^openshift-'.")
+	o.flags.DurationVar(&o.ResyncPeriod, "resync-period", 30*time.Second, "Resync period of the informers, 0 to disable the resync.")
+	o.flags.Var(&o.ResyncPeriods, "resync-periods", "Comma-separated resync periods per collector overriding --resync-period, e.g. 'deploymentconfigs=5m,routes=1h'.")
+	o.flags.Float32Var(&o.KubeAPIQPS, "kube-api-qps", 20, "Maximum queries per second to the API server, shared by all collectors.")
+	o.flags.IntVar(&o.KubeAPIBurst, "kube-api-burst", 40, "Maximum burst of queries to the API server above --kube-api-qps, shared by all collectors.")
+	o.flags.DurationVar(&o.AppliedClusterResourceQuotaPollInterval, "acrq-poll-interval", 0, "Interval to read appliedclusterresourcequotas in the background. Defaults to 0, reading them on demand during the scrape.")
+	o.flags.IntVar(&o.AppliedClusterResourceQuotaParallelism, "acrq-parallelism", 5, "Number of namespaces to read appliedclusterresourcequotas from concurrently, if all namespaces are selected.")
+	o.flags.DurationVar(&o.AppliedClusterResourceQuotaRequestTimeout, "acrq-request-timeout", 0, "Timeout for reading the appliedclusterresourcequotas of a single namespace. Defaults to 0, no timeout.")
+	o.flags.DurationVar(&o.AppliedClusterResourceQuotaTimeout, "acrq-timeout", 0, "Deadline for reading the appliedclusterresourcequotas of all namespaces, in-flight requests are cancelled and remaining namespaces are skipped. Defaults to 0, no deadline.")
+	o.flags.Var(&o.MetricWhitelist, "metric-whitelist", "Comma-separated list of metrics to be exposed, regular expressions like 'oapi_route_.*' are supported, without commas. The whitelist and blacklist are mutually exclusive.")
+	o.flags.Var(&o.MetricBlacklist, "metric-blacklist", "Comma-separated list of metrics not to be enabled, regular expressions like 'oapi_.*_labels' are supported, without commas. The whitelist and blacklist are mutually exclusive.")
+	o.flags.Var(&o.MetricLabelsAllowlist, "metric-labels-allowlist", "Kubernetes label keys per collector converted to Prometheus labels in the *_labels metrics, e.g. 'deploymentconfigs=[app,team],routes=[*]'. Collectors not listed convert all labels.")
+	o.flags.Var(&o.MetricAnnotationsAllowlist, "metric-annotations-allowlist", "Kubernetes annotation keys per collector converted to Prometheus labels in the *_annotations metrics, e.g. 'deploymentconfigs=[owner]'. Collectors not listed convert no annotations.")
 	o.flags.BoolVarP(&o.Version, "version", "", false, "oapi-exporter build version information")
 	//o.flags.BoolVarP(&o.DisablePodNonGenericResourceMetrics, "disable-pod-non-generic-resource-metrics", "", false, "Disable pod non generic resource request and limit metrics")
 	//o.flags.BoolVarP(&o.DisableNodeNonGenericResourceMetrics, "disable-node-non-generic-resource-metrics", "", false, "Disable node non generic resource request and limit metrics")
-	//o.flags.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", false, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header.")
+	o.flags.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", true, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header. Set to false to disable compression.")
+	o.flags.BoolVar(&o.EnableOpenMetrics, "enable-openmetrics", false, "Expose the metrics in the OpenMetrics format when requested by clients via 'Accept: application/openmetrics-text' header.")
 }
 
 func (o *Options) Parse() error {
@@ -0,0 +1,191 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	corev1 "k8s.io/api/core/v1"
+
+	projectv1meta "github.com/openshift/api/project/v1"
+	projectv1clientset "github.com/openshift/client-go/project/clientset/versioned"
+)
+
+const (
+	projectRequesterAnnotation    = "openshift.io/requester"
+	projectDisplayNameAnnotation  = "openshift.io/display-name"
+	projectNodeSelectorAnnotation = "openshift.io/node-selector"
+)
+
+var (
+	descProjectLabelsName          = "oapi_project_labels"
+	descProjectLabelsHelp          = "Project labels converted to Prometheus labels."
+	descProjectLabelsDefaultLabels = []string{"namespace"}
+
+	projectPhases = []corev1.NamespacePhase{
+		corev1.NamespaceActive,
+		corev1.NamespaceTerminating,
+	}
+
+	descProjectCreated = prometheus.NewDesc(
+		"oapi_project_created",
+		"Unix creation timestamp of Project",
+		[]string{"namespace"}, nil,
+	)
+
+	descProjectStatusPhase = prometheus.NewDesc(
+		"oapi_project_status_phase",
+		"The current lifecycle phase of the Project.",
+		[]string{"namespace", "phase"}, nil,
+	)
+
+	descProjectInfo = prometheus.NewDesc(
+		"oapi_project_info",
+		"Information about the Project from its openshift.io annotations.",
+		[]string{"namespace", "requester", "display_name", "node_selector"}, nil,
+	)
+
+	descProjectLabels = prometheus.NewDesc(
+		descProjectLabelsName,
+		descProjectLabelsHelp,
+		descProjectLabelsDefaultLabels, nil,
+	)
+)
+
+
+type ProjectLister func() ([]projectv1meta.Project, error)
+
+func (l ProjectLister) List() ([]projectv1meta.Project, error) {
+	return l()
+}
+
+func RegisterProjectCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
+
+	/* Note: OAPI only provides very specifiy clientsets */
+	projectClient, err := projectv1clientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access projects api: %v", err)
+	}
+
+	resyncPeriod := gen.resyncPeriod("projects")
+
+	client := projectClient.ProjectV1().RESTClient()
+
+	// note: projects are not namespaced, filter at collection
+	prlw := cache.NewListWatchFromClient(client, "projects", "", fields.Everything())
+	prinf := cache.NewSharedInformer(prlw, &projectv1meta.Project{}, resyncPeriod)
+
+	projectLister := ProjectLister(func() (projects []projectv1meta.Project, err error) {
+		for _, pr := range prinf.GetStore().List() {
+			projects = append(projects, *(pr.(*projectv1meta.Project)))
+		}
+		return projects, nil
+	})
+
+	if err := registry.Register(&projectCollector{store: projectLister, namespaces: gen.namespaces}); err != nil {
+		return err
+	}
+	gen.health.addInformer("projects", prinf.HasSynced)
+	go prinf.Run(ctx.Done())
+	return nil
+}
+
+type projectStore interface {
+	List() ([]projectv1meta.Project, error)
+}
+
+// projectCollector collects metrics about all projects in the cluster.
+type projectCollector struct {
+	store projectStore
+	namespaces *namespaceSelection
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (pc *projectCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descProjectCreated
+	ch <- descProjectStatusPhase
+	ch <- descProjectInfo
+	ch <- descProjectLabels
+}
+
+// Collect implements the prometheus.Collector interface.
+func (pc *projectCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	prs, err := pc.store.List()
+	if err != nil {
+		glog.Errorf("listing projects failed: %s", err)
+		return
+	}
+
+	count := 0
+	for _, pr := range prs {
+		if pc.namespaces.matches(pr.Name) {
+			pc.collectProject(ch, pr)
+			count++
+		}
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("project").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "project"}).Observe(float64(count))
+
+	glog.Infof("collected %d projects", count)
+}
+
+func projectLabelsDesc(labelKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descProjectLabelsName,
+		descProjectLabelsHelp,
+		append(descProjectLabelsDefaultLabels, labelKeys...),
+		nil,
+	)
+}
+
+
+func (pc *projectCollector) collectProject(ch chan<- prometheus.Metric, pr projectv1meta.Project) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{pr.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("projects", pr.Labels)
+	addGauge(projectLabelsDesc(labelKeys), 1, labelValues...)
+	if !pr.CreationTimestamp.IsZero() {
+		addGauge(descProjectCreated, float64(pr.CreationTimestamp.Unix()))
+	}
+
+	for _, p := range projectPhases {
+		addGauge(descProjectStatusPhase, boolFloat64(pr.Status.Phase == p), string(p))
+	}
+
+	addGauge(descProjectInfo, 1,
+		pr.Annotations[projectRequesterAnnotation],
+		pr.Annotations[projectDisplayNameAnnotation],
+		pr.Annotations[projectNodeSelectorAnnotation],
+	)
+}
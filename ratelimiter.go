@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var (
+	KubeAPIRateLimiterWaitHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "oapi_kube_api_rate_limiter_wait_seconds",
+			Help:    "Time the requests to the API server waited for the client side rate limiter (--kube-api-qps, --kube-api-burst).",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5, 10, 30},
+		},
+	)
+)
+
+/* instrumentedRateLimiter: token bucket shared by all API clients of the exporter,
+  the time waited in Accept is observed in KubeAPIRateLimiterWaitHistogram */
+type instrumentedRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+func newKubeAPIRateLimiter(qps float32, burst int) flowcontrol.RateLimiter {
+	return &instrumentedRateLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst)}
+}
+
+func (l *instrumentedRateLimiter) Accept() {
+	start := time.Now()
+	l.RateLimiter.Accept()
+	KubeAPIRateLimiterWaitHistogram.Observe(time.Since(start).Seconds())
+}
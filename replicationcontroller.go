@@ -0,0 +1,168 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"strconv"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/api/core/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	/* set by the deploymentconfig controller on the replication controllers of a deployment */
+	deploymentConfigNameAnnotation          = "openshift.io/deployment-config.name"
+	deploymentConfigLatestVersionAnnotation = "openshift.io/deployment-config.latest-version"
+	deploymentPhaseAnnotation               = "openshift.io/deployment.phase"
+	deploymentCancelledAnnotation           = "openshift.io/deployment.cancelled"
+)
+
+var (
+	deploymentPhases = []string{"New", "Pending", "Running", "Complete", "Failed"}
+
+	descDeploymentConfigDeploymentCreated = prometheus.NewDesc(
+		"oapi_deploymentconfig_deployment_created",
+		"Unix creation timestamp of the replication controller of a deployment of a DeploymentConfig",
+		[]string{"namespace", "deploymentconfig", "replicationcontroller"}, nil,
+	)
+
+	descDeploymentConfigDeploymentStatusPhase = prometheus.NewDesc(
+		"oapi_deploymentconfig_deployment_status_phase",
+		"The current phase of a deployment of a DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig", "replicationcontroller", "phase"}, nil,
+	)
+
+	descDeploymentConfigDeploymentVersion = prometheus.NewDesc(
+		"oapi_deploymentconfig_deployment_version",
+		"The version number of a deployment of a DeploymentConfig.",
+		[]string{"namespace", "deploymentconfig", "replicationcontroller"}, nil,
+	)
+
+	descDeploymentConfigDeploymentCancelled = prometheus.NewDesc(
+		"oapi_deploymentconfig_deployment_cancelled",
+		"Whether a deployment of a DeploymentConfig was cancelled.",
+		[]string{"namespace", "deploymentconfig", "replicationcontroller"}, nil,
+	)
+)
+
+
+type ReplicationControllerLister func() ([]v1.ReplicationController, error)
+
+func (l ReplicationControllerLister) List() ([]v1.ReplicationController, error) {
+	return l()
+}
+
+/*  RegisterReplicationControllerCollector: register collector for the deployments of DeploymentConfigs
+  NOTE: all replication controllers are watched, the ones not owned by a DeploymentConfig are skipped at collection
+*/
+func RegisterReplicationControllerCollector(ctx context.Context, registry prometheus.Registerer, kubeClient kubeclientset.Interface, gen *collectorGeneration) error {
+
+	client := kubeClient.CoreV1().RESTClient()
+
+	rcinf := newNamespacedInformer(client, "replicationcontrollers", &v1.ReplicationController{}, gen)
+
+	replicationControllerLister := ReplicationControllerLister(func() (rcs []v1.ReplicationController, err error) {
+		for _, rc := range rcinf.List() {
+			rcs = append(rcs, *(rc.(*v1.ReplicationController)))
+		}
+		return rcs, nil
+	})
+
+	if err := registry.Register(&replicationControllerCollector{store: replicationControllerLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("replicationcontrollers", rcinf.HasSynced)
+	go rcinf.Run(ctx.Done())
+	return nil
+}
+
+type replicationControllerStore interface {
+	List() ([]v1.ReplicationController, error)
+}
+
+// replicationControllerCollector collects metrics about the deployments of all deploymentconfigs in the cluster.
+type replicationControllerCollector struct {
+	store replicationControllerStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (rcc *replicationControllerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descDeploymentConfigDeploymentCreated
+	ch <- descDeploymentConfigDeploymentStatusPhase
+	ch <- descDeploymentConfigDeploymentVersion
+	ch <- descDeploymentConfigDeploymentCancelled
+}
+
+// Collect implements the prometheus.Collector interface.
+func (rcc *replicationControllerCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	rcs, err := rcc.store.List()
+	if err != nil {
+		glog.Errorf("listing replicationcontrollers failed: %s", err)
+		return
+	}
+
+	count := 0
+	for _, rc := range rcs {
+		if _, ok := rc.Annotations[deploymentConfigNameAnnotation]; ok {
+			rcc.collectReplicationController(ch, rc)
+			count++
+		}
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("replicationcontroller").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "replicationcontroller"}).Observe(float64(count))
+
+	glog.Infof("collected %d deploymentconfig replicationcontrollers", count)
+}
+
+
+func (rcc *replicationControllerCollector) collectReplicationController(ch chan<- prometheus.Metric, rc v1.ReplicationController) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{rc.Namespace, rc.Annotations[deploymentConfigNameAnnotation], rc.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	if !rc.CreationTimestamp.IsZero() {
+		addGauge(descDeploymentConfigDeploymentCreated, float64(rc.CreationTimestamp.Unix()))
+	}
+
+	phase := rc.Annotations[deploymentPhaseAnnotation]
+	for _, p := range deploymentPhases {
+		addGauge(descDeploymentConfigDeploymentStatusPhase, boolFloat64(phase == p), p)
+	}
+
+	if v, ok := rc.Annotations[deploymentConfigLatestVersionAnnotation]; ok {
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			glog.Errorf("Error converting deployment version of %s/%s to int: %s", rc.Namespace, rc.Name, err)
+		} else {
+			addGauge(descDeploymentConfigDeploymentVersion, float64(version))
+		}
+	}
+
+	addGauge(descDeploymentConfigDeploymentCancelled, boolFloat64(rc.Annotations[deploymentCancelledAnnotation] == "true"))
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/api/core/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+var (
+	descResourceQuotaCreated = prometheus.NewDesc(
+		"oapi_resourcequota_created",
+		"Unix creation timestamp of resourcequota",
+		[]string{"resourcequota", "namespace"}, nil,
+	)
+	descResourceQuota = prometheus.NewDesc(
+		"oapi_resourcequota",
+		"Information about resource requests and limits of resourcequota.",
+		[]string{
+			"resourcequota",
+			"namespace",
+			"resource",
+			"type",
+		}, nil,
+	)
+)
+
+
+type ResourceQuotaLister func() ([]v1.ResourceQuota, error)
+
+func (l ResourceQuotaLister) List() ([]v1.ResourceQuota, error) {
+	return l()
+}
+
+/*  RegisterResourceQuotaCollector: register collector for the namespace ResourceQuotas
+  NOTE: the labels match the ones of the clusterresourcequota metrics
+*/
+func RegisterResourceQuotaCollector(ctx context.Context, registry prometheus.Registerer, kubeClient kubeclientset.Interface, gen *collectorGeneration) error {
+
+	client := kubeClient.CoreV1().RESTClient()
+
+	rqinf := newNamespacedInformer(client, "resourcequotas", &v1.ResourceQuota{}, gen)
+
+	resourceQuotaLister := ResourceQuotaLister(func() (resourcequotas []v1.ResourceQuota, err error) {
+		for _, rq := range rqinf.List() {
+			resourcequotas = append(resourcequotas, *(rq.(*v1.ResourceQuota)))
+		}
+		return resourcequotas, nil
+	})
+
+	if err := registry.Register(&namespaceResourceQuotaCollector{store: resourceQuotaLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("resourcequotas", rqinf.HasSynced)
+	go rqinf.Run(ctx.Done())
+	return nil
+}
+
+type resourceQuotaStore interface {
+	List() ([]v1.ResourceQuota, error)
+}
+
+// namespaceResourceQuotaCollector collects metrics about all resourcequotas in the cluster.
+type namespaceResourceQuotaCollector struct {
+	store resourceQuotaStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (rqc *namespaceResourceQuotaCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descResourceQuotaCreated
+	ch <- descResourceQuota
+}
+
+// Collect implements the prometheus.Collector interface.
+func (rqc *namespaceResourceQuotaCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	rqs, err := rqc.store.List()
+	if err != nil {
+		glog.Errorf("listing resourcequotas failed: %s", err)
+		return
+	}
+
+	for _, rq := range rqs {
+		rqc.collectResourceQuota(ch, rq)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("resourcequotas").Observe(duration.Seconds())
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "resourcequotas"}).Observe(float64(len(rqs)))
+
+	glog.Infof("collected %d resourcequotas", len(rqs))
+}
+
+
+func (rqc *namespaceResourceQuotaCollector) collectResourceQuota(ch chan<- prometheus.Metric, rq v1.ResourceQuota) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{rq.Name, rq.Namespace}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	if !rq.CreationTimestamp.IsZero() {
+		addGauge(descResourceQuotaCreated, float64(rq.CreationTimestamp.Unix()))
+	}
+	for res, qty := range rq.Status.Hard {
+		addGauge(descResourceQuota, float64(qty.MilliValue())/1000, string(res), "hard")
+	}
+	for res, qty := range rq.Status.Used {
+		addGauge(descResourceQuota, float64(qty.MilliValue())/1000, string(res), "used")
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/rest"
+	corev1 "k8s.io/api/core/v1"
+
+	routev1meta "github.com/openshift/api/route/v1"
+	routev1clientset "github.com/openshift/client-go/route/clientset/versioned"
+)
+
+var (
+	descRouteLabelsName          = "oapi_route_labels"
+	descRouteLabelsHelp          = "Route labels converted to Prometheus labels."
+	descRouteLabelsDefaultLabels = []string{"namespace", "route"}
+
+	descRouteCreated = prometheus.NewDesc(
+		"oapi_route_created",
+		"Unix creation timestamp of Route",
+		[]string{"namespace", "route"}, nil,
+	)
+
+	descRouteInfo = prometheus.NewDesc(
+		"oapi_route_info",
+		"Information about the Route.",
+		[]string{"namespace", "route", "host", "path", "service", "tls_termination", "wildcard_policy"}, nil,
+	)
+
+	descRouteStatusAdmitted = prometheus.NewDesc(
+		"oapi_route_status_admitted",
+		"Whether the Route is admitted by the router, 0 with an empty router if no router picked it up yet.",
+		[]string{"namespace", "route", "router"}, nil,
+	)
+
+	descRouteTLSCertificateExpiry = prometheus.NewDesc(
+		"oapi_route_tls_certificate_expiry",
+		"Unix timestamp when the TLS certificate configured in the Route expires.",
+		[]string{"namespace", "route"}, nil,
+	)
+
+	descRouteLabels = prometheus.NewDesc(
+		descRouteLabelsName,
+		descRouteLabelsHelp,
+		descRouteLabelsDefaultLabels, nil,
+	)
+)
+
+
+type RouteLister func() ([]routev1meta.Route, error)
+
+func (l RouteLister) List() ([]routev1meta.Route, error) {
+	return l()
+}
+
+func RegisterRouteCollectorOApi(ctx context.Context, registry prometheus.Registerer, kubeConfig *rest.Config, gen *collectorGeneration) error {
+
+	/* Note: OAPI only provides very specifiy clientsets */
+	routeClient, err := routev1clientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to access routes api: %v", err)
+	}
+
+	client := routeClient.RouteV1().RESTClient()
+
+	rtinf := newNamespacedInformer(client, "routes", &routev1meta.Route{}, gen)
+
+	routeLister := RouteLister(func() (routes []routev1meta.Route, err error) {
+		for _, rt := range rtinf.List() {
+			routes = append(routes, *(rt.(*routev1meta.Route)))
+		}
+		return routes, nil
+	})
+
+	if err := registry.Register(&routeCollector{store: routeLister}); err != nil {
+		return err
+	}
+	gen.health.addInformer("routes", rtinf.HasSynced)
+	go rtinf.Run(ctx.Done())
+	return nil
+}
+
+type routeStore interface {
+	List() ([]routev1meta.Route, error)
+}
+
+// routeCollector collects metrics about all routes in the cluster.
+type routeCollector struct {
+	store routeStore
+}
+
+
+// Describe implements the prometheus.Collector interface.
+func (rc *routeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descRouteCreated
+	ch <- descRouteInfo
+	ch <- descRouteStatusAdmitted
+	ch <- descRouteTLSCertificateExpiry
+	ch <- descRouteLabels
+}
+
+// Collect implements the prometheus.Collector interface.
+func (rc *routeCollector) Collect(ch chan<- prometheus.Metric) {
+
+	/* collect metrics for execution times */
+	start := time.Now()
+
+	rts, err := rc.store.List()
+	if err != nil {
+		glog.Errorf("listing routes failed: %s", err)
+		return
+	}
+
+	for _, rt := range rts {
+		rc.collectRoute(ch, rt)
+	}
+
+	duration := time.Since(start)
+	ScrapeDurationHistogram.WithLabelValues("route").Observe(duration.Seconds())
+
+	ResourcesPerScrapeMetric.With(prometheus.Labels{"resource": "route"}).Observe(float64(len(rts)))
+
+	glog.Infof("collected %d routes", len(rts))
+}
+
+func routeLabelsDesc(labelKeys []string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		descRouteLabelsName,
+		descRouteLabelsHelp,
+		append(descRouteLabelsDefaultLabels, labelKeys...),
+		nil,
+	)
+}
+
+/* certificateNotAfter: expiry of the first certificate of a PEM encoded chain, i.e. the server certificate */
+func certificateNotAfter(certificate string) (time.Time, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+
+func (rc *routeCollector) collectRoute(ch chan<- prometheus.Metric, rt routev1meta.Route) {
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{rt.Namespace, rt.Name}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+	labelKeys, labelValues := kubeLabelsToPrometheusLabels("routes", rt.Labels)
+	addGauge(routeLabelsDesc(labelKeys), 1, labelValues...)
+	if !rt.CreationTimestamp.IsZero() {
+		addGauge(descRouteCreated, float64(rt.CreationTimestamp.Unix()))
+	}
+
+	tlsTermination := ""
+	if rt.Spec.TLS != nil {
+		tlsTermination = string(rt.Spec.TLS.Termination)
+	}
+	addGauge(descRouteInfo, 1, rt.Spec.Host, rt.Spec.Path, rt.Spec.To.Name, tlsTermination, string(rt.Spec.WildcardPolicy))
+
+	for _, ingress := range rt.Status.Ingress {
+		admitted := false
+		for _, c := range ingress.Conditions {
+			if c.Type == routev1meta.RouteAdmitted && c.Status == corev1.ConditionTrue {
+				admitted = true
+			}
+		}
+		addGauge(descRouteStatusAdmitted, boolFloat64(admitted), ingress.RouterName)
+	}
+	/* not picked up by any router yet */
+	if len(rt.Status.Ingress) == 0 {
+		addGauge(descRouteStatusAdmitted, 0, "")
+	}
+
+	if rt.Spec.TLS != nil && rt.Spec.TLS.Certificate != "" {
+		notAfter, err := certificateNotAfter(rt.Spec.TLS.Certificate)
+		if err != nil {
+			glog.Errorf("Error parsing TLS certificate of route %s/%s: %s", rt.Namespace, rt.Name, err)
+		} else {
+			addGauge(descRouteTLSCertificateExpiry, float64(notAfter.Unix()))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+/* tlsReloader: serving certificate and optional client CA of the metrics and telemetry servers,
+  reloaded when the files change, e.g. when the serving-cert secret is rotated */
+type tlsReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu     sync.RWMutex
+	config *tls.Config
+	stamp  string
+}
+
+func newTLSReloader(certFile, keyFile, clientCAFile string) (*tlsReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both --tls-cert-file and --tls-private-key-file are required for TLS")
+	}
+	r := &tlsReloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+/* fileStamp: changes with the modification time or size of any of the files */
+func (r *tlsReloader) fileStamp() string {
+	parts := []string{}
+	for _, f := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			parts = append(parts, f+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", f, fi.ModTime().UnixNano(), fi.Size()))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *tlsReloader) load() error {
+	stamp := r.fileStamp()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if r.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA %s", r.clientCAFile)
+		}
+		/* required per path in requireClientCert, the probes send no client certificate */
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	r.mu.Lock()
+	r.config = config
+	r.stamp = stamp
+	r.mu.Unlock()
+	return nil
+}
+
+/* watch polls the files and reloads them on change, on errors the previous certificate is kept */
+func (r *tlsReloader) watch(interval time.Duration, stopCh <-chan struct{}) {
+	go wait.Until(func() {
+		r.mu.RLock()
+		changed := r.stamp != r.fileStamp()
+		r.mu.RUnlock()
+		if !changed {
+			return
+		}
+		if err := r.load(); err != nil {
+			glog.Errorf("Failed to reload TLS files, keeping the previous ones: %v", err)
+			return
+		}
+		glog.Infof("Reloaded TLS certificate %s", r.certFile)
+	}, interval, stopCh)
+}
+
+func (r *tlsReloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+/* serverConfig: per connection the current certificate and client CA are used */
+func (r *tlsReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+/* requireClientCert: with a client CA all paths but the probes /healthz and /readyz
+  require a verified client certificate */
+func (r *tlsReloader) requireClientCert(next http.Handler) http.Handler {
+	if r.clientCAFile == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != healthzPath && req.URL.Path != readyzPath && (req.TLS == nil || len(req.TLS.VerifiedChains) == 0) {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+/* listenAndServe: plain HTTP without TLS files, HTTPS otherwise */
+func listenAndServe(server *http.Server, tlsFiles *tlsReloader) error {
+	if tlsFiles == nil {
+		return server.ListenAndServe()
+	}
+	server.TLSConfig = tlsFiles.serverConfig()
+	server.Handler = tlsFiles.requireClientCert(server.Handler)
+	return server.ListenAndServeTLS("", "")
+}
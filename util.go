@@ -1,9 +1,14 @@
-
 package main
 
 import (
 	/* Only for utils: */
+	"fmt"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
 )
 
 /* Util functions: */
@@ -15,14 +20,73 @@ func boolFloat64(b bool) float64 {
 	return 0
 }
 
-func kubeLabelsToPrometheusLabels(labels map[string]string) ([]string, []string) {
-	labelKeys := make([]string, len(labels))
-	labelValues := make([]string, len(labels))
-	i := 0
-	for k, v := range labels {
-		labelKeys[i] = "label_" + sanitizeLabelName(k)
-		labelValues[i] = v
-		i++
+var (
+	/* set via --metric-labels-allowlist and --metric-annotations-allowlist, keyed by collector,
+	  replaced on reload of the --config file while collectors may be running */
+	allowlistsMu               sync.RWMutex
+	metricLabelsAllowlist      = labelsAllowList{}
+	metricAnnotationsAllowlist = labelsAllowList{}
+
+	/* label collisions already logged, keyed by collector and the skipped key, so they are reported once and not per scrape */
+	reportedLabelCollisions sync.Map
+)
+
+func setAllowlists(labels, annotations labelsAllowList) {
+	allowlistsMu.Lock()
+	defer allowlistsMu.Unlock()
+	metricLabelsAllowlist = labels
+	metricAnnotationsAllowlist = annotations
+}
+
+/* kubeLabelsToPrometheusLabels: converts the allowed labels of the collector, all labels if the collector is not in the allowlist */
+func kubeLabelsToPrometheusLabels(collector string, labels map[string]string) ([]string, []string) {
+	allowlistsMu.RLock()
+	allowed, ok := metricLabelsAllowlist[collector]
+	allowlistsMu.RUnlock()
+	if !ok {
+		allowed = []string{"*"}
+	}
+	return kubeMapToPrometheusLabels(collector, "label", labels, allowed)
+}
+
+/* kubeAnnotationsToPrometheusLabels: converts the allowed annotations of the collector, none if the collector is not in the allowlist */
+func kubeAnnotationsToPrometheusLabels(collector string, annotations map[string]string) ([]string, []string) {
+	allowlistsMu.RLock()
+	allowed := metricAnnotationsAllowlist[collector]
+	allowlistsMu.RUnlock()
+	return kubeMapToPrometheusLabels(collector, "annotation", annotations, allowed)
+}
+
+/* kubeMapToPrometheusLabels: keys sanitized to the same label name are reported once per collector and only the first one (sorted) is kept */
+func kubeMapToPrometheusLabels(collector, prefix string, m map[string]string, allowed []string) ([]string, []string) {
+	keys := []string{}
+	if len(allowed) == 1 && allowed[0] == "*" {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	} else {
+		for _, k := range allowed {
+			if _, ok := m[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	labelKeys := make([]string, 0, len(keys))
+	labelValues := make([]string, 0, len(keys))
+	seen := make(map[string]string, len(keys))
+	for _, k := range keys {
+		name := prefix + "_" + sanitizeLabelName(k)
+		if other, ok := seen[name]; ok {
+			if _, reported := reportedLabelCollisions.LoadOrStore(collector+"/"+prefix+"/"+k, struct{}{}); !reported {
+				glog.Warningf("%s: %ss %q and %q are both converted to %q, skipping %q", collector, prefix, other, k, name, k)
+			}
+			continue
+		}
+		seen[name] = k
+		labelKeys = append(labelKeys, name)
+		labelValues = append(labelValues, m[k])
 	}
 	return labelKeys, labelValues
 }
@@ -31,4 +95,82 @@ func sanitizeLabelName(s string) string {
 	invalidLabelCharRE        := regexp.MustCompile(`[^a-zA-Z0-9_]`)
   
 	return invalidLabelCharRE.ReplaceAllString(s, "_")
-}
\ No newline at end of file
+}
+
+
+// labelsAllowList maps a collector to the kubernetes label or annotation keys
+// to convert, set via a flag like deploymentconfigs=[app,team],routes=[*].
+type labelsAllowList map[string][]string
+
+var labelsAllowListEntryRE = regexp.MustCompile(`^\s*([a-z]+)=\[([^\]]*)\]\s*(,|$)`)
+
+func (l *labelsAllowList) String() string {
+	s := *l
+	entries := []string{}
+	for collector, keys := range s {
+		entries = append(entries, fmt.Sprintf("%s=[%s]", collector, strings.Join(keys, ",")))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (l *labelsAllowList) Set(value string) error {
+	for rest := value; strings.TrimSpace(rest) != ""; {
+		m := labelsAllowListEntryRE.FindStringSubmatch(rest)
+		if m == nil {
+			return fmt.Errorf("invalid allowlist %q, expected e.g. deploymentconfigs=[app,team]", rest)
+		}
+		rest = rest[len(m[0]):]
+
+		if err := l.add(m[1], strings.Split(m[2], ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* add validates and appends the keys of the collector, also used for the --config file */
+func (l *labelsAllowList) add(collector string, keys []string) error {
+	s := *l
+	_, ok1 := availableCollectors[collector]
+	_, ok2 := availableCollectorsOApi[collector]
+	if !(ok1 || ok2) {
+		return fmt.Errorf("collector %q of allowlist does not exist", collector)
+	}
+
+	/* collision detection: keys converted to the same Prometheus label name */
+	seen := make(map[string]string)
+	for _, key := range s[collector] {
+		seen[sanitizeLabelName(key)] = key
+	}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if other, ok := seen[sanitizeLabelName(key)]; ok {
+			if other == key {
+				continue
+			}
+			return fmt.Errorf("keys %q and %q of allowlist for %s are both converted to %q", other, key, collector, sanitizeLabelName(key))
+		}
+		seen[sanitizeLabelName(key)] = key
+		s[collector] = append(s[collector], key)
+	}
+	if len(s[collector]) > 1 {
+		for _, key := range s[collector] {
+			if key == "*" {
+				return fmt.Errorf("allowlist for %s mixes * with keys", collector)
+			}
+		}
+	}
+	if _, ok := s[collector]; !ok {
+		/* an empty list disables the conversion */
+		s[collector] = []string{}
+	}
+	return nil
+}
+
+func (l *labelsAllowList) Type() string {
+	return "string"
+}
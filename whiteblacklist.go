@@ -0,0 +1,234 @@
+/*
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/* prometheus.Desc has no accessor for its name, so it is taken from String() */
+var descFqNameRE = regexp.MustCompile(`^Desc{fqName: "([^"]*)"`)
+
+func descFqName(desc *prometheus.Desc) string {
+	m := descFqNameRE.FindStringSubmatch(desc.String())
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// WhiteBlackList filters metric names by a list of regular expressions,
+// which either select the metrics to expose (whitelist) or to drop (blacklist).
+type WhiteBlackList struct {
+	list        []*regexp.Regexp
+	isWhiteList bool
+}
+
+// NewWhiteBlackList creates the filter, the whitelist and blacklist are mutually exclusive.
+func NewWhiteBlackList(whitelist, blacklist MetricSet) (*WhiteBlackList, error) {
+	if !whitelist.isEmpty() && !blacklist.isEmpty() {
+		return nil, errors.New("whitelist and blacklist are both set, they are mutually exclusive, only one of them can be set")
+	}
+
+	l := &WhiteBlackList{isWhiteList: !whitelist.isEmpty()}
+	patterns := blacklist
+	if l.isWhiteList {
+		patterns = whitelist
+	}
+	ps := patterns.asSlice()
+	sort.Strings(ps)
+	for _, p := range ps {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric pattern %q: %v", p, err)
+		}
+		l.list = append(l.list, re)
+	}
+	return l, nil
+}
+
+// IsExcluded returns whether the metric with the given name is filtered.
+func (l *WhiteBlackList) IsExcluded(name string) bool {
+	matched := false
+	for _, re := range l.list {
+		if re.MatchString(name) {
+			matched = true
+			break
+		}
+	}
+	return matched != l.isWhiteList
+}
+
+// Status describes the filter for logging.
+func (l *WhiteBlackList) Status() string {
+	patterns := []string{}
+	for _, re := range l.list {
+		patterns = append(patterns, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+	}
+	if l.isWhiteList {
+		return "whitelisting the following metrics: " + strings.Join(patterns, ", ")
+	}
+	return "blacklisting the following metrics: " + strings.Join(patterns, ", ")
+}
+
+
+/* filteredRegisterer: wraps the registry, so the collectors only describe and collect
+  the metrics passing the filter. Collectors with all metrics filtered are not registered at all,
+  but their informers still run. */
+type filteredRegisterer struct {
+	prometheus.Registerer
+	filter *WhiteBlackList
+}
+
+func newFilteredRegisterer(registry prometheus.Registerer, filter *WhiteBlackList) prometheus.Registerer {
+	if filter == nil || (len(filter.list) == 0 && !filter.isWhiteList) {
+		return registry
+	}
+	return &filteredRegisterer{Registerer: registry, filter: filter}
+}
+
+func (r *filteredRegisterer) Register(c prometheus.Collector) error {
+	fc := &filteredCollector{collector: c, filter: r.filter, excluded: make(map[*prometheus.Desc]bool)}
+
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(descs)
+		close(descs)
+	}()
+	included := 0
+	for desc := range descs {
+		ex := r.filter.IsExcluded(descFqName(desc))
+		fc.excluded[desc] = ex
+		if !ex {
+			included++
+		}
+	}
+	if included == 0 {
+		return nil
+	}
+	return r.Registerer.Register(fc)
+}
+
+func (r *filteredRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *filteredRegisterer) Unregister(c prometheus.Collector) bool {
+	return r.Registerer.Unregister(&filteredCollector{collector: c, filter: r.filter})
+}
+
+type filteredCollector struct {
+	collector prometheus.Collector
+	filter    *WhiteBlackList
+	/* decisions for the static descs of Describe, read-only after Register */
+	excluded map[*prometheus.Desc]bool
+}
+
+// Describe implements the prometheus.Collector interface.
+func (fc *filteredCollector) Describe(ch chan<- *prometheus.Desc) {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		fc.collector.Describe(descs)
+		close(descs)
+	}()
+	for desc := range descs {
+		if !fc.filter.IsExcluded(descFqName(desc)) {
+			ch <- desc
+		}
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (fc *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		fc.collector.Collect(metrics)
+		close(metrics)
+	}()
+
+	/* parsing the name from Desc.String() is the expensive part, so the decision is looked up
+	  by desc pointer. Only the label descs created per object are parsed on each scrape. */
+	for m := range metrics {
+		desc := m.Desc()
+		ex, ok := fc.excluded[desc]
+		if !ok {
+			ex = fc.filter.IsExcluded(descFqName(desc))
+		}
+		if !ex {
+			ch <- m
+		}
+	}
+}
+
+
+// MetricSet is a set of metric name patterns, set via a comma-separated flag,
+// so a pattern of the flag cannot contain a comma, e.g. in a {m,n} quantifier.
+type MetricSet map[string]struct{}
+
+func (ms *MetricSet) String() string {
+	ss := ms.asSlice()
+	sort.Strings(ss)
+	return strings.Join(ss, ",")
+}
+
+func (ms *MetricSet) Set(value string) error {
+	for _, metric := range strings.Split(value, ",") {
+		if err := ms.add(metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds a single pattern, which may contain commas, an invalid regular expression is an error.
+func (ms *MetricSet) add(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+	if _, err := regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+		return fmt.Errorf("invalid metric pattern %q: %v", pattern, err)
+	}
+	(*ms)[pattern] = struct{}{}
+	return nil
+}
+
+func (ms MetricSet) asSlice() []string {
+	metrics := []string{}
+	for metric := range ms {
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func (ms MetricSet) isEmpty() bool {
+	return len(ms) == 0
+}
+
+func (ms *MetricSet) Type() string {
+	return "string"
+}